@@ -0,0 +1,71 @@
+// See the LICENSE file for license details.
+
+package postgres
+
+import (
+	"sync"
+)
+
+// -----------------------------------------------------------------------------
+
+const (
+	defaultAutoPrepareMaxStatements = 256
+
+	// Number of distinct tracked sentences, relative to the max statements, that resets the counters
+	autoPrepareTrackingFactor = 16
+)
+
+// -----------------------------------------------------------------------------
+
+// autoPrepare keeps track of the number of times each SQL sentence is executed in order to decide
+// which ones should be prepared.
+type autoPrepare struct {
+	mutex         sync.Mutex
+	threshold     int
+	maxStatements int
+	counts        map[string]int
+	prepared      map[string]struct{}
+}
+
+// -----------------------------------------------------------------------------
+
+func newAutoPrepare(threshold int, maxStatements int) *autoPrepare {
+	if maxStatements <= 0 {
+		maxStatements = defaultAutoPrepareMaxStatements
+	}
+	return &autoPrepare{
+		mutex:         sync.Mutex{},
+		threshold:     threshold,
+		maxStatements: maxStatements,
+		counts:        make(map[string]int),
+		prepared:      make(map[string]struct{}),
+	}
+}
+
+// shouldPrepare counts an execution of the given SQL sentence and returns true if it reached the
+// threshold and must be executed as a prepared statement.
+func (ap *autoPrepare) shouldPrepare(sql string) bool {
+	ap.mutex.Lock()
+	defer ap.mutex.Unlock()
+
+	if _, ok := ap.prepared[sql]; ok {
+		return true
+	}
+	if len(ap.prepared) >= ap.maxStatements {
+		return false
+	}
+
+	count := ap.counts[sql] + 1
+	if count >= ap.threshold {
+		delete(ap.counts, sql)
+		ap.prepared[sql] = struct{}{}
+		return true
+	}
+
+	// Avoid unbounded growth if the application executes many distinct sentences
+	if len(ap.counts) >= ap.maxStatements*autoPrepareTrackingFactor {
+		ap.counts = make(map[string]int)
+	}
+	ap.counts[sql] = count
+	return false
+}
@@ -0,0 +1,57 @@
+// See the LICENSE file for license details.
+
+package postgres
+
+import (
+	"strconv"
+	"testing"
+)
+
+// -----------------------------------------------------------------------------
+
+func TestAutoPrepareThreshold(t *testing.T) {
+	ap := newAutoPrepare(3, 0)
+
+	for idx, expected := range []bool{false, false, true, true} {
+		if ap.shouldPrepare(`SELECT 1`) != expected {
+			t.Fatalf("unexpected result [execution=%d/expected=%v]", idx+1, expected)
+		}
+	}
+	if ap.shouldPrepare(`SELECT 2`) {
+		t.Fatal("statement was prepared before reaching the threshold")
+	}
+}
+
+func TestAutoPrepareMaxStatements(t *testing.T) {
+	ap := newAutoPrepare(1, 2)
+
+	if !ap.shouldPrepare(`SELECT 1`) || !ap.shouldPrepare(`SELECT 2`) {
+		t.Fatal("statements were not prepared")
+	}
+	if ap.shouldPrepare(`SELECT 3`) {
+		t.Fatal("statement was prepared beyond the limit")
+	}
+	if !ap.shouldPrepare(`SELECT 1`) {
+		t.Fatal("already prepared statement was not kept")
+	}
+}
+
+func TestAutoPrepareCountersReset(t *testing.T) {
+	ap := newAutoPrepare(2, 1)
+
+	if ap.shouldPrepare(`SELECT 0`) {
+		t.Fatal("statement was prepared before reaching the threshold")
+	}
+
+	// Fill the tracked sentences up to the limit so the counters are reset
+	for idx := 1; idx <= autoPrepareTrackingFactor; idx++ {
+		_ = ap.shouldPrepare(`SELECT ` + strconv.Itoa(idx))
+	}
+
+	if ap.shouldPrepare(`SELECT 0`) {
+		t.Fatal("counters were not reset")
+	}
+	if !ap.shouldPrepare(`SELECT 0`) {
+		t.Fatal("statement was not prepared after reaching the threshold")
+	}
+}
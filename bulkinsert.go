@@ -0,0 +1,162 @@
+// See the LICENSE file for license details.
+
+package postgres
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// -----------------------------------------------------------------------------
+
+const (
+	maxQueryParameters = 65535
+	maxBulkInsertRows  = 1000
+)
+
+// -----------------------------------------------------------------------------
+
+// BulkInsertReturning inserts the rows returned by the callback using multi-row INSERT sentences
+// and calls scan for each row returned by the RETURNING clause. Use it instead of Copy when
+// generated values, like serial ids, must be retrieved.
+//
+// Rows are sent in chunks to honor the query parameters limit. All the chunks are executed within
+// a single transaction. The callback must return nil to indicate there are no more rows.
+func (db *Database) BulkInsertReturning(
+	ctx context.Context, tableName string, columns []string, returningColumns []string, rows CopyCallback,
+	scan func(row Row) error,
+) (int64, error) {
+	var count int64
+
+	if len(returningColumns) == 0 {
+		return 0, errors.New("no returning columns specified")
+	}
+
+	sbSuffix := strings.Builder{}
+	_, _ = sbSuffix.WriteString(" RETURNING ")
+	for idx, col := range returningColumns {
+		if idx > 0 {
+			_, _ = sbSuffix.WriteString(", ")
+		}
+		_, _ = sbSuffix.WriteString(QuoteIdentifier(col))
+	}
+
+	err := db.bulkInsert(ctx, tableName, columns, rows, sbSuffix.String(), func(ctx context.Context, tx *Tx, sql string, args []interface{}) error {
+		return tx.QueryRows(ctx, sql, args...).Do(func(ctx context.Context, row Row) (bool, error) {
+			err := scan(row)
+			if err != nil {
+				return false, err
+			}
+			count += 1
+			return true, nil
+		})
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	// Done
+	return count, nil
+}
+
+// BulkInsertIgnore inserts the rows returned by the callback like BulkInsertReturning does but
+// rows that conflict with existing ones, for e.g. because of a duplicate key, are skipped using
+// `ON CONFLICT DO NOTHING`. The returned count only includes the rows that were actually inserted,
+// summed across all the chunks.
+func (db *Database) BulkInsertIgnore(ctx context.Context, tableName string, columns []string, rows CopyCallback) (int64, error) {
+	var count int64
+
+	err := db.bulkInsert(ctx, tableName, columns, rows, " ON CONFLICT DO NOTHING RETURNING 1", func(ctx context.Context, tx *Tx, sql string, args []interface{}) error {
+		// NOTE: Count the returned rows so skipped ones are never included.
+		return tx.QueryRows(ctx, sql, args...).Do(func(ctx context.Context, row Row) (bool, error) {
+			count += 1
+			return true, nil
+		})
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	// Done
+	return count, nil
+}
+
+// bulkInsert builds multi-row INSERT sentences, ended with the given suffix, with the rows returned
+// by the callback and calls exec for each chunk within a single transaction.
+func (db *Database) bulkInsert(
+	ctx context.Context, tableName string, columns []string, rows CopyCallback, suffix string,
+	exec func(ctx context.Context, tx *Tx, sql string, args []interface{}) error,
+) error {
+	if len(columns) == 0 {
+		return errors.New("no columns specified")
+	}
+
+	rowsPerChunk := maxQueryParameters / len(columns)
+	if rowsPerChunk > maxBulkInsertRows {
+		rowsPerChunk = maxBulkInsertRows
+	}
+
+	// Build the fixed part of the sentence
+	sbPrefix := strings.Builder{}
+	_, _ = sbPrefix.WriteString("INSERT INTO " + db.quoteTableName(tableName) + " (")
+	for idx, col := range columns {
+		if idx > 0 {
+			_, _ = sbPrefix.WriteString(", ")
+		}
+		_, _ = sbPrefix.WriteString(QuoteIdentifier(col))
+	}
+	_, _ = sbPrefix.WriteString(") VALUES ")
+
+	return db.WithinTx(ctx, func(ctx context.Context, tx *Tx) error {
+		rowIdx := 0
+		for {
+			// Collect the rows of the next chunk
+			args := make([]interface{}, 0, rowsPerChunk*len(columns))
+			for rowCount := 0; rowCount < rowsPerChunk; rowCount++ {
+				data, err := rows(ctx, rowIdx)
+				if err != nil {
+					return err
+				}
+				if data == nil {
+					break
+				}
+				if len(data) != len(columns) {
+					return errors.New("row values count does not match columns count [row=" + strconv.Itoa(rowIdx) + "]")
+				}
+				args = append(args, data...)
+				rowIdx += 1
+			}
+			if len(args) == 0 {
+				return nil
+			}
+
+			// Build and execute the sentence
+			sb := strings.Builder{}
+			_, _ = sb.WriteString(sbPrefix.String())
+			for ofs := 0; ofs < len(args); ofs += len(columns) {
+				if ofs > 0 {
+					_, _ = sb.WriteString(", ")
+				}
+				_, _ = sb.WriteRune('(')
+				for idx := range columns {
+					if idx > 0 {
+						_, _ = sb.WriteString(", ")
+					}
+					_, _ = sb.WriteString("$" + strconv.Itoa(ofs+idx+1))
+				}
+				_, _ = sb.WriteRune(')')
+			}
+			_, _ = sb.WriteString(suffix)
+
+			err := exec(ctx, tx, sb.String(), args)
+			if err != nil {
+				return err
+			}
+			if len(args) < rowsPerChunk*len(columns) {
+				return nil
+			}
+		}
+	})
+}
@@ -0,0 +1,186 @@
+// See the LICENSE file for license details.
+
+package postgres
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// -----------------------------------------------------------------------------
+
+const (
+	cacheListenerInitialBackoff = time.Second
+	cacheListenerMaxBackoff     = 30 * time.Second
+)
+
+// -----------------------------------------------------------------------------
+
+// cacheListeners keeps the background listeners used to invalidate cached query results.
+type cacheListeners struct {
+	mutex     sync.Mutex
+	ctx       context.Context
+	cancelCtx context.CancelFunc
+	wg        sync.WaitGroup
+	channels  map[string]*cacheListener
+}
+
+// cacheListener listens for notifications on a channel and increments the generation included in
+// the cache keys of the queries bound to it, so previously cached results are no longer used.
+type cacheListener struct {
+	channel    string
+	generation atomic.Uint64
+}
+
+// -----------------------------------------------------------------------------
+
+// QueryRowsCachedInvalidatable executes a SQL query like QueryRowsCached does but the cached result
+// is discarded as soon as a notification is received on the given channel.
+//
+// A dedicated connection, not taken from the pool, listens on the channel in the background. It is
+// established on the first call for each channel and closed when the database is closed. If it
+// cannot be established, the cache is bypassed. If the connection is lost, all the results bound
+// to the channel are discarded because notifications may have been missed.
+//
+// The notifications are usually sent by triggers on the tables read by the query, for e.g.:
+//
+//	CREATE FUNCTION notify_users_changed() RETURNS trigger AS $$
+//	BEGIN
+//	    PERFORM pg_notify('users_changed', '');
+//	    RETURN NULL;
+//	END;
+//	$$ LANGUAGE plpgsql;
+//
+//	CREATE TRIGGER users_changed AFTER INSERT OR UPDATE OR DELETE OR TRUNCATE ON users
+//	    FOR EACH STATEMENT EXECUTE FUNCTION notify_users_changed();
+//
+// Discarded results are not removed from the cache storage but they are never read again and
+// expire after the specified duration.
+func (db *Database) QueryRowsCachedInvalidatable(
+	ctx context.Context, channel string, ttl time.Duration, sql string, args ...interface{},
+) Rows {
+	if db.queryCache == nil {
+		return db.QueryRows(ctx, sql, args...)
+	}
+	key, err := getQueryCacheKey(sql, args)
+	if err != nil {
+		return db.QueryRows(ctx, sql, args...)
+	}
+	l, err := db.getCacheListener(ctx, channel)
+	if err != nil {
+		return db.QueryRows(ctx, sql, args...)
+	}
+
+	// Bind the key to the channel and its current generation
+	key = strconv.Quote(channel) + ":" + strconv.FormatUint(l.generation.Load(), 10) + ":" + key
+	return db.queryRowsCached(ctx, key, ttl, sql, args)
+}
+
+func (db *Database) getCacheListener(ctx context.Context, channel string) (*cacheListener, error) {
+	db.cacheListeners.mutex.Lock()
+	defer db.cacheListeners.mutex.Unlock()
+
+	l, ok := db.cacheListeners.channels[channel]
+	if ok {
+		return l, nil
+	}
+	if db.cacheListeners.channels == nil {
+		db.cacheListeners.channels = make(map[string]*cacheListener)
+		db.cacheListeners.ctx, db.cacheListeners.cancelCtx = context.WithCancel(context.Background())
+	}
+	if db.cacheListeners.ctx.Err() != nil {
+		return nil, errors.New("database is closed")
+	}
+
+	// Establish the first connection synchronously so results cached from now on are invalidated
+	l = &cacheListener{
+		channel: channel,
+	}
+	conn, err := db.connectCacheListener(ctx, channel)
+	if err != nil {
+		return nil, err
+	}
+	db.cacheListeners.channels[channel] = l
+
+	db.cacheListeners.wg.Add(1)
+	go func() {
+		defer db.cacheListeners.wg.Done()
+		db.runCacheListener(db.cacheListeners.ctx, l, conn)
+	}()
+
+	// Done
+	return l, nil
+}
+
+func (db *Database) connectCacheListener(ctx context.Context, channel string) (*pgx.Conn, error) {
+	pool := db.pool.Load()
+	if pool == nil {
+		return nil, errors.New("database is closed")
+	}
+	conn, err := pgx.ConnectConfig(ctx, pool.Config().ConnConfig.Copy())
+	if err != nil {
+		return nil, err
+	}
+	_, err = conn.Exec(ctx, "LISTEN "+QuoteIdentifier(channel))
+	if err != nil {
+		_ = conn.Close(context.Background())
+		return nil, err
+	}
+	return conn, nil
+}
+
+func (db *Database) runCacheListener(ctx context.Context, l *cacheListener, conn *pgx.Conn) {
+	var err error
+
+	backoff := cacheListenerInitialBackoff
+	for {
+		// Wait for notifications until the connection fails or the database is closed
+		for {
+			_, err = conn.WaitForNotification(ctx)
+			if err != nil {
+				break
+			}
+			l.generation.Add(1)
+			backoff = cacheListenerInitialBackoff
+		}
+		_ = conn.Close(context.Background())
+
+		// Notifications may have been missed
+		l.generation.Add(1)
+
+		// Reconnect
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+			if backoff > cacheListenerMaxBackoff {
+				backoff = cacheListenerMaxBackoff
+			}
+
+			conn, err = db.connectCacheListener(ctx, l.channel)
+			if err == nil {
+				l.generation.Add(1)
+				break
+			}
+		}
+	}
+}
+
+func (db *Database) stopCacheListeners() {
+	db.cacheListeners.mutex.Lock()
+	if db.cacheListeners.cancelCtx != nil {
+		db.cacheListeners.cancelCtx()
+	}
+	db.cacheListeners.mutex.Unlock()
+
+	db.cacheListeners.wg.Wait()
+}
@@ -0,0 +1,84 @@
+// See the LICENSE file for license details.
+
+package postgres
+
+import (
+	"context"
+)
+
+// -----------------------------------------------------------------------------
+
+const (
+	queryChanBufferSize = 100
+)
+
+// -----------------------------------------------------------------------------
+
+// RowResult holds a row delivered by QueryChan. If Err is set, the query failed and no more rows will
+// be delivered.
+type RowResult struct {
+	// Values contains the row values indexed by column name.
+	Values map[string]interface{}
+	Err    error
+}
+
+// -----------------------------------------------------------------------------
+
+// QueryChan executes a SQL query and delivers the returned rows through a channel, so they can be
+// processed by other goroutines while the rest are being fetched. The channel is closed once all the
+// rows were delivered, after delivering a result with an error or when the context is cancelled.
+//
+// The consumer must drain the channel or cancel the context, else the connection is not released.
+func (db *Database) QueryChan(ctx context.Context, sql string, args ...interface{}) (<-chan RowResult, error) {
+	ctx = db.withAcquireTracking(ctx)
+
+	rows, err := db.pool.Load().Query(ctx, sql, db.withQueryExecMode(ctx, sql, args)...)
+	if err != nil {
+		return nil, db.handleError(ctx, db.newAcquireError(ctx, err, "unable to run query"))
+	}
+
+	ch := make(chan RowResult, queryChanBufferSize)
+	go func() {
+		defer close(ch)
+		defer rows.Close()
+
+		fields := rows.FieldDescriptions()
+		for rows.Next() {
+			values, err := rows.Values()
+			if err != nil {
+				sendRowResult(ctx, ch, RowResult{
+					Err: db.handleError(ctx, newError(err, "unable to scan row")),
+				})
+				return
+			}
+
+			result := RowResult{
+				Values: make(map[string]interface{}, len(fields)),
+			}
+			for idx, fd := range fields {
+				result.Values[fd.Name] = values[idx]
+			}
+			if !sendRowResult(ctx, ch, result) {
+				return
+			}
+		}
+		err := rows.Err()
+		if err != nil {
+			sendRowResult(ctx, ch, RowResult{
+				Err: db.handleError(ctx, newError(err, "unable to run query")),
+			})
+		}
+	}()
+
+	// Done
+	return ch, nil
+}
+
+func sendRowResult(ctx context.Context, ch chan<- RowResult, result RowResult) bool {
+	select {
+	case ch <- result:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
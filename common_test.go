@@ -3,10 +3,13 @@
 package postgres_test
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/json"
 	"flag"
 	"testing"
+
+	"github.com/mxmauro/go-postgres/v2"
 )
 
 // -----------------------------------------------------------------------------
@@ -67,6 +70,59 @@ func checkSettings(t *testing.T) {
 	}
 }
 
+func openTestDatabase(ctx context.Context, t *testing.T) *postgres.Database {
+	var db *postgres.Database
+	var err error
+
+	// Parse and check command-line parameters
+	flag.Parse()
+	checkSettings(t)
+
+	// Create database driver
+	if len(pgUrl) > 0 {
+		db, err = postgres.NewFromURL(ctx, pgUrl)
+	} else {
+		db, err = postgres.New(ctx, postgres.Options{
+			Host:     pgHost,
+			Port:     uint16(pgPort),
+			User:     pgUsername,
+			Password: pgPassword,
+			Name:     pgDatabaseName,
+		})
+	}
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+	return db
+}
+
+// openTestDatabaseWithOptions creates a database driver using the host settings and lets the caller
+// customize the options. Tests using it are skipped if a URL was provided.
+func openTestDatabaseWithOptions(ctx context.Context, t *testing.T, setup func(opts *postgres.Options)) *postgres.Database {
+	// Parse and check command-line parameters
+	flag.Parse()
+	if len(pgUrl) > 0 {
+		t.Skip("custom options require host settings")
+	}
+	checkSettings(t)
+
+	opts := postgres.Options{
+		Host:     pgHost,
+		Port:     uint16(pgPort),
+		User:     pgUsername,
+		Password: pgPassword,
+		Name:     pgDatabaseName,
+	}
+	setup(&opts)
+
+	// Create database driver
+	db, err := postgres.New(ctx, opts)
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+	return db
+}
+
 func addressOf[T any](x T) *T {
 	return &x
 }
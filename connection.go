@@ -4,8 +4,9 @@ package postgres
 
 import (
 	"context"
+	"errors"
+	"strings"
 
-	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -17,6 +18,21 @@ type Conn struct {
 	conn *pgxpool.Conn
 }
 
+// ResetScope indicates which session state is discarded by Conn.Reset.
+type ResetScope int
+
+const (
+	// ResetAll discards all the session state. Equivalent to the scopes below plus resetting the
+	// run-time parameters, releasing advisory locks and unlistening channels.
+	ResetAll ResetScope = iota
+	// ResetPlans releases all the prepared statements and cached query plans.
+	ResetPlans
+	// ResetSequences discards the cached sequence values.
+	ResetSequences
+	// ResetTemp drops all the temporary tables created in the session.
+	ResetTemp
+)
+
 // -----------------------------------------------------------------------------
 
 // DB returns the underlying database driver.
@@ -26,27 +42,66 @@ func (c *Conn) DB() *Database {
 
 // Exec executes an SQL statement within the single connection.
 func (c *Conn) Exec(ctx context.Context, sql string, args ...interface{}) (int64, error) {
+	err := c.db.validateExecSql(ctx, c.conn.Conn().PgConn(), sql, args)
+	if err != nil {
+		return 0, c.db.handleError(ctx, err)
+	}
 	affectedRows := int64(0)
-	ct, err := c.conn.Exec(ctx, sql, args...)
+	ct, err := c.conn.Exec(ctx, sql, c.db.withQueryExecMode(ctx, sql, args)...)
 	if err == nil {
 		affectedRows = ct.RowsAffected()
 	} else {
 		err = newError(err, "")
 	}
-	return affectedRows, c.db.handleError(err)
+	return affectedRows, c.db.handleError(ctx, err)
+}
+
+// ExecTag executes an SQL statement within the single connection and returns the command tag details.
+func (c *Conn) ExecTag(ctx context.Context, sql string, args ...interface{}) (CommandTag, error) {
+	err := c.db.validateExecSql(ctx, c.conn.Conn().PgConn(), sql, args)
+	if err != nil {
+		return CommandTag{}, c.db.handleError(ctx, err)
+	}
+	tag := CommandTag{}
+	ct, err := c.conn.Exec(ctx, sql, c.db.withQueryExecMode(ctx, sql, args)...)
+	if err == nil {
+		tag = newCommandTag(ct)
+	} else {
+		err = newError(err, "unable to execute command")
+	}
+	return tag, c.db.handleError(ctx, err)
 }
 
 // QueryRow executes a SQL query within the single connection.
 func (c *Conn) QueryRow(ctx context.Context, sql string, args ...interface{}) Row {
+	err := c.db.validateSql(ctx, c.conn.Conn().PgConn(), sql, args)
+	if err != nil {
+		return &rowGetter{
+			ctx: ctx,
+			db:  c.db,
+			err: err,
+		}
+	}
+	rows, err := c.conn.Query(ctx, sql, c.db.withQueryExecMode(ctx, sql, args)...)
 	return &rowGetter{
-		db:  c.db,
-		row: c.conn.QueryRow(ctx, sql, args...),
+		ctx:  ctx,
+		db:   c.db,
+		rows: rows,
+		err:  newError(err, "unable to scan row"),
 	}
 }
 
 // QueryRows executes a SQL query within the single connection.
 func (c *Conn) QueryRows(ctx context.Context, sql string, args ...interface{}) Rows {
-	rows, err := c.conn.Query(ctx, sql, args...)
+	err := c.db.validateSql(ctx, c.conn.Conn().PgConn(), sql, args)
+	if err != nil {
+		return &rowsGetter{
+			db:  c.db,
+			ctx: ctx,
+			err: err,
+		}
+	}
+	rows, err := c.conn.Query(ctx, sql, c.db.withQueryExecMode(ctx, sql, args)...)
 	return &rowsGetter{
 		db:   c.db,
 		ctx:  ctx,
@@ -55,11 +110,51 @@ func (c *Conn) QueryRows(ctx context.Context, sql string, args ...interface{}) R
 	}
 }
 
-// Copy executes a SQL copy query within the single connection.
+// Reset returns the session to a clean state by executing `DISCARD` with the given scopes, or
+// `DISCARD ALL` if none is specified. Use it before reusing a pinned connection for unrelated
+// operations so leftover settings or temporary tables do not leak.
+//
+// DISCARD ALL cannot be executed inside a transaction block, so calling Reset with ResetAll on a
+// connection with an open transaction fails.
+func (c *Conn) Reset(ctx context.Context, scopes ...ResetScope) error {
+	if len(scopes) == 0 {
+		scopes = []ResetScope{ResetAll}
+	}
+
+	resetPlans := false
+	sb := strings.Builder{}
+	for _, scope := range scopes {
+		switch scope {
+		case ResetAll:
+			_, _ = sb.WriteString("DISCARD ALL;")
+			resetPlans = true
+		case ResetPlans:
+			_, _ = sb.WriteString("DISCARD PLANS; DEALLOCATE ALL;")
+			resetPlans = true
+		case ResetSequences:
+			_, _ = sb.WriteString("DISCARD SEQUENCES;")
+		case ResetTemp:
+			_, _ = sb.WriteString("DISCARD TEMP;")
+		default:
+			return errors.New("invalid reset scope")
+		}
+	}
+
+	// NOTE: Sentences without arguments are sent using the simple protocol so all of them are
+	//       executed in a single roundtrip.
+	_, err := c.conn.Exec(ctx, sb.String())
+	if err == nil && resetPlans {
+		// Prepared statements were released so also clear the statement cache of the connection
+		err = c.conn.Conn().DeallocateAll(ctx)
+	}
+	return c.db.handleError(ctx, newError(err, "unable to reset connection"))
+}
+
+// Copy executes a SQL copy query within the single connection. See Database.Copy.
 func (c *Conn) Copy(ctx context.Context, tableName string, columnNames []string, callback CopyCallback) (int64, error) {
 	n, err := c.conn.CopyFrom(
 		ctx,
-		pgx.Identifier{tableName},
+		c.db.getTableIdentifier(tableName),
 		columnNames,
 		&copyWithCallback{
 			ctx: ctx,
@@ -68,35 +163,31 @@ func (c *Conn) Copy(ctx context.Context, tableName string, columnNames []string,
 	)
 
 	// Done
-	return n, c.db.handleError(newError(err, "unable to execute command"))
+	return n, c.db.handleError(ctx, newError(err, "unable to execute command"))
 }
 
 // WithinTx executes a callback function within the context of a single connection.
 func (c *Conn) WithinTx(ctx context.Context, cb WithinTxCallback, opts ...WithinTxOptions) error {
-	txOpts := pgx.TxOptions{
-		IsoLevel:       pgx.ReadCommitted,
-		AccessMode:     pgx.ReadWrite,
-		DeferrableMode: pgx.NotDeferrable,
-	}
-	if len(opts) > 0 {
-		if opts[0].ReadOnly {
-			txOpts.AccessMode = pgx.ReadOnly
-		}
-		if opts[0].RepeatableRead {
-			txOpts.IsoLevel = pgx.RepeatableRead
-		}
-	}
-
-	innerTx, err := c.conn.BeginTx(ctx, txOpts)
+	innerTx, err := c.conn.BeginTx(ctx, getTxOptions(opts))
 	if err == nil {
-		err = cb(ctx, Tx{
-			db: c.db,
-			tx: innerTx,
+		hooks := newTxHooks(nil)
+		stats := newTxStats()
+		ctx = stats.attach(hooks.attach(ctx))
+		err = cb(ctx, &Tx{
+			db:    c.db,
+			tx:    innerTx,
+			hooks: hooks,
+			stats: stats,
 		})
 		if err == nil {
-			err = innerTx.Commit(ctx)
-			if err != nil {
-				err = newError(err, "unable to commit db transaction")
+			err = hooks.runBeforeCommit(ctx)
+			if err == nil {
+				err = innerTx.Commit(ctx)
+				if err != nil {
+					err = newError(err, "unable to commit db transaction")
+				}
+			} else {
+				err = newError(err, "before commit callback returned failure")
 			}
 		} else {
 			err = newError(err, "callback returned failure")
@@ -104,8 +195,9 @@ func (c *Conn) WithinTx(ctx context.Context, cb WithinTxCallback, opts ...Within
 		if err != nil {
 			_ = innerTx.Rollback(context.Background()) // Using context.Background() on purpose
 		}
+		hooks.finish(err == nil)
 	} else {
 		err = newError(err, "unable to start transaction")
 	}
-	return c.db.handleError(err)
+	return c.db.handleError(ctx, err)
 }
@@ -0,0 +1,111 @@
+// See the LICENSE file for license details.
+
+package postgres
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// -----------------------------------------------------------------------------
+
+type noErrorLatchCtxKey struct{}
+
+type acquireStartTimeCtxKey struct{}
+
+type connAcquiredCtxKey struct{}
+
+type rawErrorsCtxKey struct{}
+
+type queryExecModeCtxKey struct{}
+
+// QueryExecMode defines how a statement is sent to the server.
+type QueryExecMode int
+
+const (
+	// QueryExecModeDefault uses the mode configured for the connection pool.
+	QueryExecModeDefault QueryExecMode = iota
+
+	// QueryExecModeCacheStatement prepares the statement and keeps it in the connection statement
+	// cache. Recommended for frequently executed queries.
+	QueryExecModeCacheStatement
+
+	// QueryExecModeExec sends the statement without preparing it. Recommended for one-off dynamic
+	// queries to avoid evicting other statements from the cache.
+	QueryExecModeExec
+)
+
+// -----------------------------------------------------------------------------
+
+// WithoutErrorLatch returns a new context that makes the operations executed with it skip the
+// error latch. Errors are still returned to the caller but the error handler is not notified and
+// the last error state is not modified. Useful for health checks and probes.
+func WithoutErrorLatch(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noErrorLatchCtxKey{}, true)
+}
+
+// WithRawErrors returns a new context that makes the operations executed with it return the
+// underlying library errors, like *pgconn.PgError, instead of wrapping them in an *Error. The error
+// latch and the error handler work as usual.
+//
+// TypeOfError, ConstraintKindOf and IsNoRowsError also recognize raw errors.
+func WithRawErrors(ctx context.Context) context.Context {
+	return context.WithValue(ctx, rawErrorsCtxKey{}, true)
+}
+
+// WithQueryExecMode returns a new context that makes the Exec and Query methods called with it
+// send the statements using the specified mode instead of the pool's default.
+func WithQueryExecMode(ctx context.Context, mode QueryExecMode) context.Context {
+	return context.WithValue(ctx, queryExecModeCtxKey{}, mode)
+}
+
+// -----------------------------------------------------------------------------
+
+func isErrorLatchDisabled(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	v, _ := ctx.Value(noErrorLatchCtxKey{}).(bool)
+	return v
+}
+
+func isRawErrorsEnabled(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	v, _ := ctx.Value(rawErrorsCtxKey{}).(bool)
+	return v
+}
+
+// withQueryExecMode prepends the exec mode stored in the context, if any, to the query arguments.
+// If no mode is set and automatic preparation is enabled, frequently executed sentences are sent
+// as prepared statements.
+func (db *Database) withQueryExecMode(ctx context.Context, sql string, args []interface{}) []interface{} {
+	mode := QueryExecModeDefault
+	if ctx != nil {
+		mode, _ = ctx.Value(queryExecModeCtxKey{}).(QueryExecMode)
+	}
+	switch mode {
+	case QueryExecModeCacheStatement:
+		return append([]interface{}{pgx.QueryExecModeCacheStatement}, args...)
+	case QueryExecModeExec:
+		return append([]interface{}{pgx.QueryExecModeExec}, args...)
+	}
+	if db.autoPrepare != nil && db.autoPrepare.shouldPrepare(sql) {
+		return append([]interface{}{pgx.QueryExecModeCacheStatement}, args...)
+	}
+	return args
+}
+
+func getAcquireStartTime(ctx context.Context) (time.Time, bool) {
+	t, ok := ctx.Value(acquireStartTimeCtxKey{}).(time.Time)
+	return t, ok
+}
+
+func isConnAcquired(ctx context.Context) bool {
+	acquired, ok := ctx.Value(connAcquiredCtxKey{}).(*atomic.Bool)
+	return ok && acquired.Load()
+}
@@ -26,6 +26,14 @@ func (c *copyWithCallback) Next() bool {
 		return false
 	}
 
+	// Stop feeding rows if the context was cancelled or its deadline exceeded
+	err = c.ctx.Err()
+	if err != nil {
+		c.err = newError(err, "")
+		c.data = nil
+		return false
+	}
+
 	c.data, err = c.cb(c.ctx, c.counter)
 	if err != nil {
 		c.err = newError(err, "")
@@ -0,0 +1,221 @@
+// See the LICENSE file for license details.
+
+package postgres
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// -----------------------------------------------------------------------------
+
+// CSVOptions defines the options used to import and export CSV content.
+type CSVOptions struct {
+	// Delimiter is the field delimiter. Defaults to a comma.
+	Delimiter rune
+
+	// Header indicates the first record contains the column names. When exporting, a header record is
+	// written with the names of the returned columns.
+	Header bool
+
+	// Columns contains the target column names if the content does not have a header. If empty, the
+	// table columns are used in order. Not used when exporting.
+	Columns []string
+
+	// NullToken is the field value that represents a NULL. Defaults to an empty field.
+	NullToken string
+}
+
+type csvColumnDecoder func(s string) (interface{}, error)
+
+// -----------------------------------------------------------------------------
+
+// CopyFromCSV imports the CSV content read from r into the specified table using a COPY command.
+//
+// Field values are converted from their text representation to the column types obtained from the
+// database catalog. Malformed records are reported along with their line number.
+func (db *Database) CopyFromCSV(ctx context.Context, tableName string, r io.Reader, opts CSVOptions) (int64, error) {
+	var n int64
+
+	err := db.WithinConn(ctx, func(ctx context.Context, conn *Conn) error {
+		var err error
+
+		n, err = conn.copyFromCSV(ctx, tableName, r, opts)
+		return err
+	})
+	return n, err
+}
+
+// QueryCSV executes a SQL query and writes the returned rows to w as CSV content including a header
+// record with the column names. NULL values are written as empty fields.
+func (db *Database) QueryCSV(ctx context.Context, w io.Writer, sql string, args ...interface{}) error {
+	return db.QueryCSVWithOptions(ctx, w, CSVOptions{
+		Header: true,
+	}, sql, args...)
+}
+
+// QueryCSVWithOptions is like QueryCSV but allows to specify the delimiter, if the header record is
+// written and the NULL representation.
+//
+// Values are formatted by the server using their text representation and fields are quoted as
+// described in RFC 4180 when needed. Rows are written as they are received.
+func (db *Database) QueryCSVWithOptions(
+	ctx context.Context, w io.Writer, opts CSVOptions, sql string, args ...interface{},
+) error {
+	ctx = db.withAcquireTracking(ctx)
+
+	// Use a cancellable context to abort the query if the writer fails
+	queryCtx, cancelQuery := context.WithCancel(ctx)
+	defer cancelQuery()
+
+	// Request all the columns in text format
+	args = append([]interface{}{pgx.QueryResultFormats{pgx.TextFormatCode}}, args...)
+	rows, err := db.pool.Load().Query(queryCtx, sql, db.withQueryExecMode(ctx, sql, args)...)
+	if err != nil {
+		return db.handleError(ctx, db.newAcquireError(ctx, err, "unable to run query"))
+	}
+	defer rows.Close()
+
+	csvWriter := csv.NewWriter(w)
+	if opts.Delimiter != 0 {
+		csvWriter.Comma = opts.Delimiter
+	}
+
+	fields := rows.FieldDescriptions()
+	record := make([]string, len(fields))
+	if opts.Header {
+		for idx, fd := range fields {
+			record[idx] = fd.Name
+		}
+		err = csvWriter.Write(record)
+		if err != nil {
+			return err
+		}
+	}
+
+	for rows.Next() {
+		for idx, v := range rows.RawValues() {
+			if v != nil {
+				record[idx] = string(v)
+			} else {
+				record[idx] = opts.NullToken
+			}
+		}
+		err = csvWriter.Write(record)
+		if err != nil {
+			cancelQuery()
+			return err
+		}
+	}
+	err = rows.Err()
+	if err != nil {
+		return db.handleError(ctx, newError(err, "unable to run query"))
+	}
+
+	// Done
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
+
+func (c *Conn) copyFromCSV(ctx context.Context, tableName string, r io.Reader, opts CSVOptions) (int64, error) {
+	// Get the table columns and their types
+	columnTypes := make(map[string]uint32)
+	tableColumns := make([]string, 0)
+	err := c.QueryRows(
+		ctx,
+		`SELECT attname, atttypid FROM pg_attribute WHERE attrelid = $1::regclass AND attnum > 0 AND
+		NOT attisdropped ORDER BY attnum`,
+		c.db.quoteTableName(tableName),
+	).Do(func(ctx context.Context, row Row) (bool, error) {
+		var name string
+		var oid uint32
+
+		err := row.Scan(&name, &oid)
+		if err == nil {
+			columnTypes[name] = oid
+			tableColumns = append(tableColumns, name)
+		}
+		return true, err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	// Setup the CSV reader
+	csvReader := csv.NewReader(r)
+	if opts.Delimiter != 0 {
+		csvReader.Comma = opts.Delimiter
+	}
+
+	columns := opts.Columns
+	if opts.Header {
+		columns, err = csvReader.Read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return 0, nil
+			}
+			return 0, fmt.Errorf("invalid CSV header [err=%v]", err.Error())
+		}
+	}
+	if len(columns) == 0 {
+		columns = tableColumns
+	}
+
+	// Build the column decoders
+	typeMap := c.conn.Conn().TypeMap()
+	decoders := make([]csvColumnDecoder, len(columns))
+	for idx, name := range columns {
+		oid, ok := columnTypes[name]
+		if !ok {
+			return 0, fmt.Errorf("column not found [name=%v]", name)
+		}
+		decoders[idx] = newCSVColumnDecoder(typeMap, oid)
+	}
+
+	// Copy records
+	return c.Copy(ctx, tableName, columns, func(ctx context.Context, _ int) ([]interface{}, error) {
+		record, err2 := csvReader.Read()
+		if err2 != nil {
+			if errors.Is(err2, io.EOF) {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("invalid CSV record [err=%v]", err2.Error())
+		}
+		if len(record) != len(columns) {
+			line, _ := csvReader.FieldPos(0)
+			return nil, fmt.Errorf("wrong number of fields in CSV record [line=%d]", line)
+		}
+
+		values := make([]interface{}, len(record))
+		for idx, field := range record {
+			if field == opts.NullToken {
+				continue
+			}
+			values[idx], err2 = decoders[idx](field)
+			if err2 != nil {
+				line, col := csvReader.FieldPos(idx)
+				return nil, fmt.Errorf("invalid CSV field [line=%d/column=%d/err=%v]", line, col, err2.Error())
+			}
+		}
+		return values, nil
+	})
+}
+
+func newCSVColumnDecoder(typeMap *pgtype.Map, oid uint32) csvColumnDecoder {
+	t, ok := typeMap.TypeForOID(oid)
+	if !ok {
+		// Unknown type, let the server convert the text
+		return func(s string) (interface{}, error) {
+			return s, nil
+		}
+	}
+	return func(s string) (interface{}, error) {
+		return t.Codec.DecodeValue(typeMap, oid, pgtype.TextFormatCode, []byte(s))
+	}
+}
@@ -0,0 +1,116 @@
+// See the LICENSE file for license details.
+
+package postgres
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// -----------------------------------------------------------------------------
+
+// QueryParams holds a SQL sentence, or a fragment of it, along with the arguments referenced by its
+// $N placeholders.
+type QueryParams struct {
+	Sql  string
+	Args []interface{}
+}
+
+// CTEBuilder composes queries with common table expressions (WITH clauses) from independent
+// fragments. Each fragment numbers its placeholders starting at $1 and the builder renumbers them
+// when they are combined.
+type CTEBuilder struct {
+	ctes []cteEntry
+}
+
+type cteEntry struct {
+	name  string
+	query QueryParams
+}
+
+// -----------------------------------------------------------------------------
+
+// NewCTEBuilder creates a new CTE builder.
+func NewCTEBuilder() *CTEBuilder {
+	return &CTEBuilder{
+		ctes: make([]cteEntry, 0),
+	}
+}
+
+// With adds a named common table expression. Expressions are emitted in the order they were added so
+// later ones and the final query can reference the previous ones by name.
+func (b *CTEBuilder) With(name string, query QueryParams) *CTEBuilder {
+	b.ctes = append(b.ctes, cteEntry{
+		name:  name,
+		query: query,
+	})
+	return b
+}
+
+// Build combines the common table expressions and the final query into a single sentence prefixed
+// with the WITH clause. Arguments are merged in the same order and placeholders renumbered to match.
+func (b *CTEBuilder) Build(finalQuery QueryParams) (QueryParams, error) {
+	if len(b.ctes) == 0 {
+		return QueryParams{}, errors.New("no common table expressions specified")
+	}
+
+	result := QueryParams{
+		Args: make([]interface{}, 0),
+	}
+	names := make(map[string]struct{}, len(b.ctes))
+
+	sb := strings.Builder{}
+	_, _ = sb.WriteString("WITH ")
+	for idx, cte := range b.ctes {
+		if len(cte.name) == 0 {
+			return QueryParams{}, errors.New("empty common table expression name")
+		}
+		if _, ok := names[cte.name]; ok {
+			return QueryParams{}, fmt.Errorf("duplicate common table expression [name=%v]", cte.name)
+		}
+		names[cte.name] = struct{}{}
+
+		sql, err := appendQueryParams(&result, cte.query)
+		if err != nil {
+			return QueryParams{}, fmt.Errorf("invalid common table expression [name=%v/err=%v]", cte.name, err.Error())
+		}
+
+		if idx > 0 {
+			_, _ = sb.WriteString(", ")
+		}
+		_, _ = sb.WriteString(QuoteIdentifier(cte.name) + " AS (" + sql + ")")
+	}
+
+	sql, err := appendQueryParams(&result, finalQuery)
+	if err != nil {
+		return QueryParams{}, fmt.Errorf("invalid final query [err=%v]", err.Error())
+	}
+	_, _ = sb.WriteString(" " + sql)
+	result.Sql = sb.String()
+
+	// Done
+	return result, nil
+}
+
+// appendQueryParams appends the arguments of the fragment to the result and returns the fragment's
+// SQL with its placeholders renumbered accordingly.
+func appendQueryParams(result *QueryParams, fragment QueryParams) (string, error) {
+	maxPlaceholder, err := getMaxSqlPlaceholder(fragment.Sql)
+	if err != nil {
+		return "", err
+	}
+	if maxPlaceholder > len(fragment.Args) {
+		return "", fmt.Errorf("statement references %d placeholders but %d arguments were provided", maxPlaceholder,
+			len(fragment.Args))
+	}
+
+	sql, err := renumberSqlPlaceholders(strings.TrimSpace(fragment.Sql), len(result.Args))
+	if err != nil {
+		return "", err
+	}
+	result.Args = append(result.Args, fragment.Args...)
+
+	// Done
+	return sql, nil
+}
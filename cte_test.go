@@ -0,0 +1,63 @@
+// See the LICENSE file for license details.
+
+package postgres_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mxmauro/go-postgres/v2"
+)
+
+// -----------------------------------------------------------------------------
+
+func TestCTEBuilder(t *testing.T) {
+	qp, err := postgres.NewCTEBuilder().
+		With("active", postgres.QueryParams{
+			Sql:  `SELECT id FROM users WHERE status = $1 AND created_at > $2`,
+			Args: []interface{}{"active", "2024-01-01"},
+		}).
+		With("orders", postgres.QueryParams{
+			Sql:  `SELECT user_id, total FROM orders WHERE total > $1 AND note <> '$1' AND user_id IN (SELECT id FROM active)`,
+			Args: []interface{}{100},
+		}).
+		Build(postgres.QueryParams{
+			Sql:  `SELECT * FROM orders WHERE user_id = $2 OR user_id = $1 -- $3`,
+			Args: []interface{}{10, 20},
+		})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	expectedSql := `WITH "active" AS (SELECT id FROM users WHERE status = $1 AND created_at > $2), ` +
+		`"orders" AS (SELECT user_id, total FROM orders WHERE total > $3 AND note <> '$1' AND user_id IN (SELECT id FROM active)) ` +
+		`SELECT * FROM orders WHERE user_id = $5 OR user_id = $4 -- $3`
+	if qp.Sql != expectedSql {
+		t.Fatalf("sql mismatch [got=%v]", qp.Sql)
+	}
+	if !reflect.DeepEqual(qp.Args, []interface{}{"active", "2024-01-01", 100, 10, 20}) {
+		t.Fatalf("args mismatch [got=%v]", qp.Args)
+	}
+}
+
+func TestCTEBuilderErrors(t *testing.T) {
+	_, err := postgres.NewCTEBuilder().Build(postgres.QueryParams{Sql: `SELECT 1`})
+	if err == nil {
+		t.Fatal("builder without expressions was accepted")
+	}
+
+	_, err = postgres.NewCTEBuilder().
+		With("a", postgres.QueryParams{Sql: `SELECT $1, $2`, Args: []interface{}{1}}).
+		Build(postgres.QueryParams{Sql: `SELECT * FROM a`})
+	if err == nil {
+		t.Fatal("missing argument was accepted")
+	}
+
+	_, err = postgres.NewCTEBuilder().
+		With("a", postgres.QueryParams{Sql: `SELECT 1`}).
+		With("a", postgres.QueryParams{Sql: `SELECT 2`}).
+		Build(postgres.QueryParams{Sql: `SELECT * FROM a`})
+	if err == nil {
+		t.Fatal("duplicate expression name was accepted")
+	}
+}
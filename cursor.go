@@ -0,0 +1,119 @@
+// See the LICENSE file for license details.
+
+package postgres
+
+import (
+	"context"
+	"strconv"
+	"sync/atomic"
+)
+
+// -----------------------------------------------------------------------------
+
+const (
+	defaultCursorBatchSize = 1000
+)
+
+// -----------------------------------------------------------------------------
+
+var cursorCounter uint64
+
+type cursorRows struct {
+	ctx  context.Context
+	db   *Database
+	tx   *Tx
+	sql  string
+	args []interface{}
+}
+
+// -----------------------------------------------------------------------------
+
+// QueryCursor executes a SQL query using a server-side cursor. Rows are retrieved from the server in
+// batches while the callback passed to Do is executed, so the whole result set is never held in
+// memory.
+//
+// Because cursors only live within a transaction, a new one is started when Do is called and it is
+// committed once all rows are processed.
+func (db *Database) QueryCursor(ctx context.Context, sql string, args ...interface{}) Rows {
+	return &cursorRows{
+		ctx:  ctx,
+		db:   db,
+		sql:  sql,
+		args: args,
+	}
+}
+
+// QueryCursor executes a SQL query within the transaction using a server-side cursor. Rows are
+// retrieved from the server in batches while the callback passed to Do is executed.
+func (tx *Tx) QueryCursor(ctx context.Context, sql string, args ...interface{}) Rows {
+	return &cursorRows{
+		ctx:  ctx,
+		db:   tx.db,
+		tx:   tx,
+		sql:  sql,
+		args: args,
+	}
+}
+
+// -----------------------------------------------------------------------------
+
+func (r *cursorRows) Do(cb ScanRowsCallback) error {
+	if r.tx != nil {
+		return r.db.handleError(r.ctx, r.fetch(r.ctx, r.tx, cb))
+	}
+	return r.db.WithinTx(r.ctx, func(ctx context.Context, tx *Tx) error {
+		return r.fetch(ctx, tx, cb)
+	})
+}
+
+func (r *cursorRows) fetch(ctx context.Context, tx *Tx, cb ScanRowsCallback) error {
+	cursorName := "_cursor_" + strconv.FormatUint(atomic.AddUint64(&cursorCounter, 1), 10)
+
+	_, err := tx.tx.Exec(ctx, "DECLARE "+cursorName+" NO SCROLL CURSOR FOR "+r.sql, r.args...)
+	if err != nil {
+		return newError(err, "unable to declare cursor")
+	}
+
+	fetchSql := "FETCH FORWARD " + strconv.Itoa(defaultCursorBatchSize) + " FROM " + cursorName
+	for {
+		rows, err := tx.tx.Query(ctx, fetchSql)
+		if err != nil {
+			return newError(err, "unable to fetch rows from cursor")
+		}
+
+		row := &rowsGetter{
+			ctx:  ctx,
+			db:   r.db,
+			rows: rows,
+		}
+		count := 0
+		cont := true
+		for cont && rows.Next() {
+			count += 1
+			cont, err = cb(ctx, row)
+			if err != nil {
+				rows.Close()
+				return newError(err, "callback returned failure")
+			}
+		}
+		rows.Close()
+		err = rows.Err()
+		if err != nil {
+			return newError(err, "unable to fetch rows from cursor")
+		}
+
+		if !cont || count < defaultCursorBatchSize {
+			break
+		}
+	}
+
+	// Close the cursor. It is automatically closed at the end of the transaction but a user
+	// transaction may continue executing other statements.
+	_, err = tx.tx.Exec(ctx, "CLOSE "+cursorName)
+	if err != nil {
+		return newError(err, "unable to close cursor")
+	}
+
+	// Done
+	return nil
+}
@@ -0,0 +1,100 @@
+// See the LICENSE file for license details.
+
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// -----------------------------------------------------------------------------
+
+// DiagnosticReport contains details about the database server and the connection pool useful for
+// troubleshooting.
+type DiagnosticReport struct {
+	ServerVersion  string
+	Database       string
+	User           string
+	SSL            bool
+	MaxConnections int
+	Pool           PoolStats
+}
+
+// PoolStats contains a snapshot of the connection pool statistics.
+type PoolStats struct {
+	MaxConns             int32
+	TotalConns           int32
+	AcquiredConns        int32
+	IdleConns            int32
+	ConstructingConns    int32
+	AcquireCount         int64
+	AcquireDuration      time.Duration
+	EmptyAcquireCount    int64
+	CanceledAcquireCount int64
+
+	// NewConnsCount and ClosedConnsCount are the cumulative number of connections opened and closed.
+	// A high churn may indicate connections are being dropped.
+	NewConnsCount           int64
+	ClosedConnsCount        int64
+	MaxLifetimeDestroyCount int64
+	MaxIdleDestroyCount     int64
+}
+
+// -----------------------------------------------------------------------------
+
+// Diagnose connects to the server and returns a report with the server version, the current
+// database and user, if the connection uses SSL, the max_connections setting and the current
+// pool statistics.
+func (db *Database) Diagnose(ctx context.Context) (DiagnosticReport, error) {
+	report := DiagnosticReport{}
+
+	pool := db.pool.Load()
+	if pool == nil {
+		return DiagnosticReport{}, errors.New("database is closed")
+	}
+
+	err := db.QueryRow(ctx, `SELECT
+		version(), current_database(), current_user,
+		COALESCE((SELECT ssl FROM pg_stat_ssl WHERE pid = pg_backend_pid()), FALSE),
+		current_setting('max_connections')::int`,
+	).Scan(&report.ServerVersion, &report.Database, &report.User, &report.SSL, &report.MaxConnections)
+	if err != nil {
+		return DiagnosticReport{}, err
+	}
+
+	report.Pool = db.getPoolStats(pool)
+
+	// Done
+	return report, nil
+}
+
+// PoolStats returns a snapshot of the connection pool statistics.
+func (db *Database) PoolStats() PoolStats {
+	pool := db.pool.Load()
+	if pool == nil {
+		return PoolStats{}
+	}
+	return db.getPoolStats(pool)
+}
+
+func (db *Database) getPoolStats(pool *pgxpool.Pool) PoolStats {
+	stat := pool.Stat()
+	return PoolStats{
+		MaxConns:                stat.MaxConns(),
+		TotalConns:              stat.TotalConns(),
+		AcquiredConns:           stat.AcquiredConns(),
+		IdleConns:               stat.IdleConns(),
+		ConstructingConns:       stat.ConstructingConns(),
+		AcquireCount:            stat.AcquireCount(),
+		AcquireDuration:         stat.AcquireDuration(),
+		EmptyAcquireCount:       stat.EmptyAcquireCount(),
+		CanceledAcquireCount:    stat.CanceledAcquireCount(),
+		NewConnsCount:           stat.NewConnsCount(),
+		ClosedConnsCount:        db.closedConns.Load(),
+		MaxLifetimeDestroyCount: stat.MaxLifetimeDestroyCount(),
+		MaxIdleDestroyCount:     stat.MaxIdleDestroyCount(),
+	}
+}
@@ -5,6 +5,8 @@ package postgres
 import (
 	"errors"
 	"strings"
+
+	"github.com/jackc/pgx/v5"
 )
 
 // -----------------------------------------------------------------------------
@@ -12,23 +14,43 @@ import (
 type ErrorType int
 
 const (
-	ErrorTypeNone                ErrorType = iota
-	ErrorTypeConnection          ErrorType = iota
-	ErrorTypePostgresGeneric     ErrorType = iota
-	ErrorTypeDuplicateKey        ErrorType = iota
-	ErrorTypeConstraintViolation ErrorType = iota
-	ErrorTypeTxSerialization     ErrorType = iota
-	ErrorTypeNoRows              ErrorType = 10000
+	ErrorTypeNone                  ErrorType = iota
+	ErrorTypeConnection            ErrorType = iota
+	ErrorTypePostgresGeneric       ErrorType = iota
+	ErrorTypeDuplicateKey          ErrorType = iota
+	ErrorTypeConstraintViolation   ErrorType = iota
+	ErrorTypeTxSerialization       ErrorType = iota
+	ErrorTypePoolSaturated         ErrorType = iota
+	ErrorTypeLockTimeout           ErrorType = iota
+	ErrorTypeUndefinedObject       ErrorType = iota
+	ErrorTypeInsufficientResources ErrorType = iota
+	ErrorTypeNoRows                ErrorType = 10000
+)
+
+// ConstraintKind indicates which kind of constraint was violated.
+type ConstraintKind int
+
+const (
+	ConstraintKindNone       ConstraintKind = iota
+	ConstraintKindNotNull    ConstraintKind = iota
+	ConstraintKindForeignKey ConstraintKind = iota
+	ConstraintKindCheck      ConstraintKind = iota
+	ConstraintKindExclusion  ConstraintKind = iota
+	ConstraintKindUnique     ConstraintKind = iota
 )
 
 // -----------------------------------------------------------------------------
 
 // Error is the error type usually returned by us.
 type Error struct {
-	message string
-	err     error // Err is the underlying error that occurred during the operation.
-	Details *ErrorDetails
-	Type    ErrorType
+	message        string
+	err            error // Err is the underlying error that occurred during the operation.
+	Details        *ErrorDetails
+	Type           ErrorType
+	ConstraintKind ConstraintKind
+
+	// TxStats is set if the error occurred within a transaction.
+	TxStats *TxStats
 }
 
 type ErrorDetails struct {
@@ -47,12 +69,20 @@ type ErrorDetails struct {
 	File           string
 	Line           int32
 	Routine        string
+
+	// ObjectName contains the name of the missing table, column or object on ErrorTypeUndefinedObject
+	// errors.
+	ObjectName string
 }
 
 // NoRowsError is the error we return if the query does not return any row.
 type NoRowsError struct {
 }
 
+// AlreadyExecutedError is the error we return if an idempotent operation was already executed.
+type AlreadyExecutedError struct {
+}
+
 // -----------------------------------------------------------------------------
 
 // Unwrap returns the underlying error.
@@ -90,25 +120,75 @@ func (e *NoRowsError) Error() string {
 	return "no rows in result set"
 }
 
+func (e *AlreadyExecutedError) Error() string {
+	return "operation already executed"
+}
+
 // -----------------------------------------------------------------------------
 
 // TypeOfError returns the type of error.
 func TypeOfError(err error) ErrorType {
-	var e *Error
-	var nre *NoRowsError
-
-	if errors.As(err, &e) {
+	e, ok := asError(err)
+	if ok {
 		return e.Type
 	}
-	if errors.As(err, &nre) {
+	if IsNoRowsError(err) {
 		return ErrorTypeNoRows
 	}
 	return ErrorTypeNone
 }
 
+// ConstraintKindOf returns the kind of constraint violated if the error is a constraint violation.
+func ConstraintKindOf(err error) ConstraintKind {
+	e, ok := asError(err)
+	if ok {
+		return e.ConstraintKind
+	}
+	return ConstraintKindNone
+}
+
 // IsNoRowsError returns true if the given error is the result of returning an empty result set.
 func IsNoRowsError(err error) bool {
 	var e *NoRowsError
 
+	return errors.As(err, &e) || errors.Is(err, pgx.ErrNoRows)
+}
+
+// IsUndefinedObjectError returns true if the given error is the result of referencing a table,
+// column or other object that does not exist.
+func IsUndefinedObjectError(err error) bool {
+	return TypeOfError(err) == ErrorTypeUndefinedObject
+}
+
+// IsTooManyConnectionsError returns true if the given error is the result of the server rejecting
+// a new connection because its max_connections limit was reached. Unlike ErrorTypePoolSaturated,
+// which means all the connections of our pool are in use, this condition is caused by the server
+// and usually transient.
+func IsTooManyConnectionsError(err error) bool {
+	e, ok := asError(err)
+	return ok && e.Type == ErrorTypeInsufficientResources && e.Details != nil && e.Details.Code == "53300"
+}
+
+// IsAlreadyExecutedError returns true if the given error is the result of skipping an idempotent
+// operation that was already executed.
+func IsAlreadyExecutedError(err error) bool {
+	var e *AlreadyExecutedError
+
 	return errors.As(err, &e)
 }
+
+// -----------------------------------------------------------------------------
+
+// asError returns the *Error wrapped in the given error. Raw library errors returned when
+// WithRawErrors is used are converted on the fly.
+func asError(err error) (*Error, bool) {
+	var e *Error
+
+	if errors.As(err, &e) {
+		return e, true
+	}
+	if errors.As(newError(err, ""), &e) {
+		return e, true
+	}
+	return nil, false
+}
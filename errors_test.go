@@ -0,0 +1,41 @@
+// See the LICENSE file for license details.
+
+package postgres_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/mxmauro/go-postgres/v2"
+)
+
+// -----------------------------------------------------------------------------
+
+func TestTooManyConnectionsError(t *testing.T) {
+	tooManyErr := &pgconn.PgError{
+		Severity: "FATAL",
+		Code:     "53300",
+		Message:  "sorry, too many clients already",
+	}
+	if postgres.TypeOfError(tooManyErr) != postgres.ErrorTypeInsufficientResources {
+		t.Fatalf("unexpected error type [type=%v]", postgres.TypeOfError(tooManyErr))
+	}
+	if !postgres.IsTooManyConnectionsError(tooManyErr) {
+		t.Fatal("too many connections error was not detected")
+	}
+
+	outOfMemoryErr := &pgconn.PgError{
+		Code: "53200",
+	}
+	if postgres.TypeOfError(outOfMemoryErr) != postgres.ErrorTypeInsufficientResources {
+		t.Fatalf("unexpected error type [type=%v]", postgres.TypeOfError(outOfMemoryErr))
+	}
+	if postgres.IsTooManyConnectionsError(outOfMemoryErr) {
+		t.Fatal("out of memory error detected as too many connections")
+	}
+
+	if postgres.IsTooManyConnectionsError(errors.New("some error")) {
+		t.Fatal("generic error detected as too many connections")
+	}
+}
@@ -0,0 +1,108 @@
+// See the LICENSE file for license details.
+
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+)
+
+// -----------------------------------------------------------------------------
+
+// ExplainResult contains the execution plan of a query returned by Explain.
+type ExplainResult struct {
+	// Plan is the root node of the plan tree.
+	Plan ExplainNode `json:"Plan"`
+
+	// PlanningTime and ExecutionTime, in milliseconds, are only set if the query was analyzed.
+	PlanningTime  float64 `json:"Planning Time"`
+	ExecutionTime float64 `json:"Execution Time"`
+
+	// Raw contains the JSON plan as returned by the server.
+	Raw json.RawMessage `json:"-"`
+}
+
+// ExplainNode contains the details of a node of the plan tree.
+type ExplainNode struct {
+	NodeType     string  `json:"Node Type"`
+	RelationName string  `json:"Relation Name"`
+	Alias        string  `json:"Alias"`
+	StartupCost  float64 `json:"Startup Cost"`
+	TotalCost    float64 `json:"Total Cost"`
+	PlanRows     float64 `json:"Plan Rows"`
+	PlanWidth    int     `json:"Plan Width"`
+
+	// The actual values, with times in milliseconds, are only set if the query was analyzed.
+	ActualStartupTime float64 `json:"Actual Startup Time"`
+	ActualTotalTime   float64 `json:"Actual Total Time"`
+	ActualRows        float64 `json:"Actual Rows"`
+	ActualLoops       float64 `json:"Actual Loops"`
+
+	Plans []ExplainNode `json:"Plans"`
+}
+
+// -----------------------------------------------------------------------------
+
+// TotalCost returns the estimated total cost of the query.
+func (r *ExplainResult) TotalCost() float64 {
+	return r.Plan.TotalCost
+}
+
+// ActualTime returns the execution time, in milliseconds, of the root node if the query was analyzed.
+func (r *ExplainResult) ActualTime() float64 {
+	return r.Plan.ActualTotalTime
+}
+
+// Rows returns the number of rows returned by the query if it was analyzed or the estimated one if not.
+func (r *ExplainResult) Rows() float64 {
+	if r.Plan.ActualLoops > 0 {
+		return r.Plan.ActualRows * r.Plan.ActualLoops
+	}
+	return r.Plan.PlanRows
+}
+
+// Explain runs `EXPLAIN (FORMAT JSON)` on the given query and returns the parsed execution plan.
+//
+// If analyze is true, the query is actually executed to collect the real execution times and row
+// counts. In that case, it runs within a transaction that is always rolled back so data modifying
+// statements do not take effect. Note that side effects outside the transaction, like sequence
+// increments, are not undone.
+func (db *Database) Explain(ctx context.Context, analyze bool, sql string, args ...interface{}) (ExplainResult, error) {
+	var data string
+	var err error
+
+	explainSql := "EXPLAIN (FORMAT JSON) " + sql
+	if analyze {
+		var tx *Tx
+
+		explainSql = "EXPLAIN (FORMAT JSON, ANALYZE) " + sql
+
+		tx, err = db.Begin(ctx)
+		if err != nil {
+			return ExplainResult{}, err
+		}
+		err = tx.QueryRow(ctx, explainSql, args...).Scan(&data)
+		_ = tx.Rollback(context.Background()) // Using context.Background() on purpose
+	} else {
+		err = db.QueryRow(ctx, explainSql, args...).Scan(&data)
+	}
+	if err != nil {
+		return ExplainResult{}, err
+	}
+
+	// The plan is returned as an array with a single element
+	results := make([]ExplainResult, 0)
+	err = json.Unmarshal([]byte(data), &results)
+	if err != nil {
+		return ExplainResult{}, err
+	}
+	if len(results) == 0 {
+		return ExplainResult{}, errors.New("empty execution plan")
+	}
+	result := results[0]
+	result.Raw = json.RawMessage(data)
+
+	// Done
+	return result, nil
+}
@@ -0,0 +1,145 @@
+// See the LICENSE file for license details.
+
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// -----------------------------------------------------------------------------
+
+// InTxCallback defines a callback called in the context of the initiated transaction that returns a value.
+type InTxCallback[T any] func(ctx context.Context, tx *Tx) (T, error)
+
+// -----------------------------------------------------------------------------
+
+// InTx executes a callback function within the context of a transaction and returns the value
+// produced by it once the transaction is committed. On error, the transaction is rolled back and the
+// zero value is returned.
+func InTx[T any](ctx context.Context, db *Database, cb InTxCallback[T]) (T, error) {
+	return InTxOpts(ctx, db, WithinTxOptions{}, cb)
+}
+
+// InTxOpts is like InTx but allows to specify the transaction options.
+func InTxOpts[T any](ctx context.Context, db *Database, opts WithinTxOptions, cb InTxCallback[T]) (T, error) {
+	var result T
+
+	err := db.WithinTx(ctx, func(ctx context.Context, tx *Tx) error {
+		var err error
+
+		result, err = cb(ctx, tx)
+		return err
+	}, opts)
+	if err != nil {
+		var zero T
+
+		return zero, err
+	}
+
+	// Done
+	return result, nil
+}
+
+// ScanJSONAgg scans a single json/jsonb column, usually the result of a `json_agg(...)` expression,
+// and unmarshals it into the destination slice. NULL values and empty arrays produce an empty slice.
+func ScanJSONAgg[T any](row Row, dest *[]T) error {
+	var data *string
+
+	err := row.Scan(&data)
+	if err != nil {
+		return err
+	}
+
+	*dest = make([]T, 0)
+	if data == nil || len(*data) == 0 {
+		return nil
+	}
+	return json.Unmarshal([]byte(*data), dest)
+}
+
+// QueryKeyValue executes a query returning two columns and builds a map from the values of the
+// first column to the values of the second one. Useful to load small lookup tables. If a key is
+// repeated, the last value wins.
+func QueryKeyValue[K comparable, V any](ctx context.Context, db Queryer, sql string, args ...interface{}) (map[K]V, error) {
+	result := make(map[K]V)
+	err := db.QueryRows(ctx, sql, args...).Do(func(ctx context.Context, row Row) (bool, error) {
+		var key K
+		var value V
+
+		err := row.Scan(&key, &value)
+		if err != nil {
+			return false, err
+		}
+		result[key] = value
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Done
+	return result, nil
+}
+
+// QueryRowsMapped executes a query and calls the mapper function for each returned row to convert
+// it into a value of the desired type. Unlike the automatic struct scanning, the mapper has full
+// control over type conversions and computed fields. An empty slice is returned if the query does
+// not return rows.
+func QueryRowsMapped[T any](
+	ctx context.Context, db Queryer, mapper func(row Row) (T, error), sql string, args ...interface{},
+) ([]T, error) {
+	result := make([]T, 0)
+	err := db.QueryRows(ctx, sql, args...).Do(func(ctx context.Context, row Row) (bool, error) {
+		value, err := mapper(row)
+		if err != nil {
+			return false, err
+		}
+		result = append(result, value)
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Done
+	return result, nil
+}
+
+// GetByKeysOrdered fetches the rows of the table whose key column matches any of the given keys
+// and returns them in the same order as the keys. The scan function must return the key and the
+// value of each row. Missing keys are represented by nil entries. Useful in dataloader patterns.
+//
+// Table names containing a dot are treated as schema-qualified, else the DefaultSchema is used.
+func GetByKeysOrdered[K comparable, T any](
+	ctx context.Context, db Queryer, tableName string, keyColumn string, keys []K, scan func(row Row) (K, T, error),
+) ([]*T, error) {
+	result := make([]*T, len(keys))
+	if len(keys) == 0 {
+		return result, nil
+	}
+
+	values := make(map[K]*T, len(keys))
+	err := db.QueryRows(
+		ctx,
+		"SELECT * FROM "+quoteTableName(getDefaultSchema(db), tableName)+" WHERE "+QuoteIdentifier(keyColumn)+" = ANY($1)",
+		keys,
+	).Do(func(ctx context.Context, row Row) (bool, error) {
+		key, value, err := scan(row)
+		if err != nil {
+			return false, err
+		}
+		values[key] = &value
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for idx, key := range keys {
+		result[idx] = values[key]
+	}
+
+	// Done
+	return result, nil
+}
@@ -4,8 +4,11 @@ package postgres
 
 import (
 	"errors"
+	"fmt"
 	"net"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
@@ -77,22 +80,53 @@ func newError(wrappedErr error, message string) error {
 
 	switch pgErr.Code {
 	case "23000":
-		fallthrough
+		e.Type = ErrorTypeConstraintViolation
+
 	case "23502":
-		fallthrough
+		e.Type = ErrorTypeConstraintViolation
+		e.ConstraintKind = ConstraintKindNotNull
+
 	case "23503":
-		fallthrough
+		e.Type = ErrorTypeConstraintViolation
+		e.ConstraintKind = ConstraintKindForeignKey
+
 	case "23514":
-		fallthrough
+		e.Type = ErrorTypeConstraintViolation
+		e.ConstraintKind = ConstraintKindCheck
+
 	case "23P01":
 		e.Type = ErrorTypeConstraintViolation
+		e.ConstraintKind = ConstraintKindExclusion
 
 	case "23505":
 		e.Type = ErrorTypeDuplicateKey
+		e.ConstraintKind = ConstraintKindUnique
 
 	case "40001":
 		e.Type = ErrorTypeTxSerialization
 
+	case "55P03":
+		e.Type = ErrorTypeLockTimeout
+
+	case "53000":
+		fallthrough
+	case "53100":
+		fallthrough
+	case "53200":
+		fallthrough
+	case "53300":
+		fallthrough
+	case "53400":
+		e.Type = ErrorTypeInsufficientResources
+
+	case "42P01":
+		fallthrough
+	case "42703":
+		fallthrough
+	case "42704":
+		e.Type = ErrorTypeUndefinedObject
+		e.Details.ObjectName = getUndefinedObjectName(pgErr)
+
 	default:
 		e.Type = ErrorTypePostgresGeneric
 	}
@@ -101,10 +135,131 @@ func newError(wrappedErr error, message string) error {
 	return e
 }
 
+func getUndefinedObjectName(pgErr *pgconn.PgError) string {
+	if len(pgErr.ColumnName) > 0 {
+		return pgErr.ColumnName
+	}
+	if len(pgErr.TableName) > 0 {
+		return pgErr.TableName
+	}
+	if len(pgErr.DataTypeName) > 0 {
+		return pgErr.DataTypeName
+	}
+
+	// The server usually does not fill the fields above so extract the name from the message, for
+	// e.g.: relation "users" does not exist
+	startIdx := strings.IndexByte(pgErr.Message, '"')
+	if startIdx >= 0 {
+		endIdx := strings.IndexByte(pgErr.Message[startIdx+1:], '"')
+		if endIdx >= 0 {
+			return pgErr.Message[startIdx+1 : startIdx+1+endIdx]
+		}
+	}
+	return ""
+}
+
+func newCommandTag(ct pgconn.CommandTag) CommandTag {
+	return CommandTag{
+		RowsAffected: ct.RowsAffected(),
+		Tag:          ct.String(),
+		Insert:       ct.Insert(),
+		Update:       ct.Update(),
+		Delete:       ct.Delete(),
+		Select:       ct.Select(),
+	}
+}
+
+func getTxOptions(opts []WithinTxOptions) pgx.TxOptions {
+	txOpts := pgx.TxOptions{
+		IsoLevel:       pgx.ReadCommitted,
+		AccessMode:     pgx.ReadWrite,
+		DeferrableMode: pgx.NotDeferrable,
+	}
+	if len(opts) > 0 {
+		if opts[0].ReadOnly {
+			txOpts.AccessMode = pgx.ReadOnly
+		}
+		if opts[0].RepeatableRead {
+			txOpts.IsoLevel = pgx.RepeatableRead
+		}
+		if opts[0].DeferConstraints {
+			// NOTE: Sentences without arguments are sent using the simple protocol so both are
+			//       executed in a single roundtrip.
+			txOpts.BeginQuery = "BEGIN ISOLATION LEVEL " + string(txOpts.IsoLevel) + " " + string(txOpts.AccessMode) +
+				"; SET CONSTRAINTS ALL DEFERRED"
+		}
+	}
+	return txOpts
+}
+
+// formatTimeoutParam converts a duration into milliseconds, the default unit of the server's
+// timeout parameters. Durations below one millisecond are rounded up to avoid disabling the timeout.
+func formatTimeoutParam(d time.Duration) string {
+	ms := d.Milliseconds()
+	if ms == 0 && d > 0 {
+		ms = 1
+	}
+	return strconv.FormatInt(ms, 10)
+}
+
 func encodeDSN(s string) string {
+	s = strings.ReplaceAll(s, "\\", "\\\\")
 	return strings.ReplaceAll(s, "'", "\\'")
 }
 
-func quoteIdentifier(s string) string {
-	return "\"" + strings.ReplaceAll(s, "\"", "\"\"") + "\""
+// isValidExtendedSettingKey returns true if the name only contains lowercase letters and underscores
+// so it cannot inject additional keywords into the connection string.
+func isValidExtendedSettingKey(s string) bool {
+	if len(s) == 0 {
+		return false
+	}
+	for _, ch := range s {
+		if (ch < 'a' || ch > 'z') && ch != '_' {
+			return false
+		}
+	}
+	return true
+}
+
+func quoteParameterName(s string) string {
+	parts := strings.Split(s, ".")
+	for idx := range parts {
+		parts[idx] = QuoteIdentifier(parts[idx])
+	}
+	return strings.Join(parts, ".")
+}
+
+func checkScanTargets(fields []pgconn.FieldDescription, dest []interface{}) error {
+	if len(fields) != len(dest) {
+		return fmt.Errorf("query returned %d columns but %d scan targets were provided", len(fields), len(dest))
+	}
+	return nil
+}
+
+func getScanTargetsByName(fields []pgconn.FieldDescription, dest map[string]interface{}) ([]interface{}, error) {
+	targets := make([]interface{}, len(fields))
+	found := 0
+	for idx, fd := range fields {
+		if d, ok := dest[fd.Name]; ok {
+			targets[idx] = d
+			found += 1
+		}
+	}
+	if found != len(dest) {
+		for name := range dest {
+			if !hasField(fields, name) {
+				return nil, fmt.Errorf("column \"%s\" not found in query result", name)
+			}
+		}
+	}
+	return targets, nil
+}
+
+func hasField(fields []pgconn.FieldDescription, name string) bool {
+	for _, fd := range fields {
+		if fd.Name == name {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,139 @@
+// See the LICENSE file for license details.
+
+package postgres
+
+import (
+	"context"
+	"hash/fnv"
+	"math"
+)
+
+// -----------------------------------------------------------------------------
+
+const (
+	onceKeyPrefix = "once:"
+)
+
+// -----------------------------------------------------------------------------
+
+// ExecIdempotent executes an SQL statement only once for the given idempotency key.
+//
+// The key is stored in the idempotency table within the same transaction that executes the
+// statement, so both succeed or fail together. If the key was already stored, the statement is
+// not executed and an AlreadyExecutedError is returned. Use IsAlreadyExecutedError to check it.
+func (db *Database) ExecIdempotent(ctx context.Context, key string, sql string, args ...interface{}) (int64, error) {
+	var affectedRows int64
+
+	err := db.createIdempotencyTable(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	tableName := QuoteIdentifier(db.idempotency.tableName)
+	skipped := false
+	err = db.WithinTx(ctx, func(ctx context.Context, tx *Tx) error {
+		n, err := tx.Exec(
+			ctx,
+			`INSERT INTO `+tableName+` (id, executed_at) VALUES ($1, NOW()) `+
+				`ON CONFLICT DO NOTHING;`,
+			key,
+		)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			skipped = true
+			return nil
+		}
+
+		affectedRows, err = tx.Exec(ctx, sql, args...)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	if skipped {
+		return 0, errAlreadyExecuted
+	}
+
+	// Done
+	return affectedRows, nil
+}
+
+// Once executes the callback within a transaction only once for the given key across all the
+// instances sharing the database, even across restarts. If the callback returns an error, the
+// transaction is rolled back and the callback can be executed again in a later call.
+//
+// Concurrent callers are serialized with an advisory lock and the key is stored in the
+// idempotency table. If the key was already stored, Once returns nil without calling the callback.
+// Keys are namespaced so they do not collide with the ones used by ExecIdempotent.
+func (db *Database) Once(ctx context.Context, key string, cb WithinTxCallback) error {
+	err := db.createIdempotencyTable(ctx)
+	if err != nil {
+		return err
+	}
+
+	key = onceKeyPrefix + key
+
+	tableName := QuoteIdentifier(db.idempotency.tableName)
+	return db.WithinTx(ctx, func(ctx context.Context, tx *Tx) error {
+		var done bool
+
+		// Wait for other instances running the same initialization
+		_, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock($1)`, db.getOnceLockId(key))
+		if err != nil {
+			return err
+		}
+
+		err = tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM `+tableName+` WHERE id = $1)`, key).Scan(&done)
+		if err != nil {
+			return err
+		}
+		if done {
+			return nil
+		}
+
+		err = cb(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(
+			ctx,
+			`INSERT INTO `+tableName+` (id, executed_at) VALUES ($1, NOW());`,
+			key,
+		)
+		return err
+	})
+}
+
+func (db *Database) getOnceLockId(key string) int64 {
+	h := fnv.New64a()
+	_, _ = h.Write(db.nameHash[:])
+	_, _ = h.Write([]byte(db.idempotency.tableName))
+	_, _ = h.Write([]byte{0})
+	_, _ = h.Write([]byte(key))
+	return int64(h.Sum64() & math.MaxInt64)
+}
+
+func (db *Database) createIdempotencyTable(ctx context.Context) error {
+	db.idempotency.mutex.Lock()
+	defer db.idempotency.mutex.Unlock()
+
+	if db.idempotency.created {
+		return nil
+	}
+
+	_, err := db.Exec(ctx,
+		`CREATE TABLE IF NOT EXISTS `+QuoteIdentifier(db.idempotency.tableName)+` (
+			id   text NOT NULL PRIMARY KEY,
+			executed_at timestamp NOT NULL
+	)`)
+	if err != nil {
+		return err
+	}
+	db.idempotency.created = true
+
+	// Done
+	return nil
+}
@@ -2,13 +2,32 @@
 
 package postgres
 
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
 // -----------------------------------------------------------------------------
 
 var errNoRows = &NoRowsError{}
+var errAlreadyExecuted = &AlreadyExecutedError{}
 
 // -----------------------------------------------------------------------------
 
-func (db *Database) handleError(err error) error {
+func (db *Database) handleError(ctx context.Context, err error) error {
+	withTxStats(ctx, err)
+
+	// Skip the error latch if the caller requested it
+	if isErrorLatchDisabled(ctx) {
+		return getRawError(ctx, err)
+	}
+
 	isOurs := true
 	switch TypeOfError(err) {
 	case ErrorTypeNone:
@@ -37,5 +56,179 @@ func (db *Database) handleError(err error) error {
 	}
 
 	// Done
+	return getRawError(ctx, err)
+}
+
+func getRawError(ctx context.Context, err error) error {
+	if err == nil || !isRawErrorsEnabled(ctx) {
+		return err
+	}
+	switch e := err.(type) {
+	case *Error:
+		if e.err != nil {
+			return e.err
+		}
+	case *NoRowsError:
+		return pgx.ErrNoRows
+	}
 	return err
 }
+
+func (db *Database) withAcquireTracking(ctx context.Context) context.Context {
+	ctx = context.WithValue(ctx, connAcquiredCtxKey{}, &atomic.Bool{})
+	if !db.trackAcquireWait {
+		return ctx
+	}
+	return context.WithValue(ctx, acquireStartTimeCtxKey{}, time.Now())
+}
+
+// newAcquireError wraps the error returned by an operation that acquires a connection from the pool
+// reporting pool saturation if the acquisition timed out. The context must be the one returned by
+// withAcquireTracking so errors raised after obtaining the connection are not misreported.
+func (db *Database) newAcquireError(ctx context.Context, err error, message string) error {
+	// If the acquisition timed out while all the connections are in use, report pool saturation
+	if errors.Is(err, context.DeadlineExceeded) && !isConnAcquired(ctx) {
+		pool := db.pool.Load()
+		if pool == nil {
+			return newError(err, message)
+		}
+		stat := pool.Stat()
+		if stat.AcquiredConns() >= stat.MaxConns() {
+			return &Error{
+				message: message,
+				err:     err,
+				Type:    ErrorTypePoolSaturated,
+			}
+		}
+	}
+	return newError(err, message)
+}
+
+// formatErrorSql returns the SQL sentence to include in error messages with its literals redacted
+// and its length limited.
+func (db *Database) formatErrorSql(sql string) string {
+	sql = RedactSQL(sql)
+	if len(sql) > db.maxErrorSqlLength {
+		if db.maxErrorSqlLength > 3 {
+			sql = truncStrBytes(sql, db.maxErrorSqlLength-3) + "..."
+		} else {
+			sql = truncStrBytes(sql, db.maxErrorSqlLength)
+		}
+	}
+	return sql
+}
+
+func (db *Database) getTableIdentifier(tableName string) pgx.Identifier {
+	return getTableIdentifier(db.defaultSchema, tableName)
+}
+
+func (db *Database) quoteTableName(tableName string) string {
+	return quoteTableName(db.defaultSchema, tableName)
+}
+
+// getTableIdentifier splits a schema-qualified table name or prepends the default schema, if set, to
+// unqualified ones.
+func getTableIdentifier(defaultSchema string, tableName string) pgx.Identifier {
+	dotIdx := strings.IndexByte(tableName, '.')
+	if dotIdx >= 0 {
+		return pgx.Identifier{tableName[:dotIdx], tableName[dotIdx+1:]}
+	}
+	if len(defaultSchema) > 0 {
+		return pgx.Identifier{defaultSchema, tableName}
+	}
+	return pgx.Identifier{tableName}
+}
+
+// quoteTableName returns the quoted table name to embed in a SQL sentence, schema-qualified if needed.
+func quoteTableName(defaultSchema string, tableName string) string {
+	id := getTableIdentifier(defaultSchema, tableName)
+	for idx := range id {
+		id[idx] = QuoteIdentifier(id[idx])
+	}
+	return strings.Join(id, ".")
+}
+
+// getDefaultSchema returns the default schema of the database the queryer belongs to, if any.
+func getDefaultSchema(q Queryer) string {
+	switch v := q.(type) {
+	case *Database:
+		return v.defaultSchema
+	case *Conn:
+		return v.db.defaultSchema
+	case *Tx:
+		return v.db.defaultSchema
+	}
+	return ""
+}
+
+// wrapPrePing wraps the given BeforeAcquire hook in order to ping the connection before handing it out.
+func wrapPrePing(beforeAcquire func(ctx context.Context, conn *pgx.Conn) bool) func(ctx context.Context, conn *pgx.Conn) bool {
+	return func(ctx context.Context, conn *pgx.Conn) bool {
+		if beforeAcquire != nil && !beforeAcquire(ctx, conn) {
+			return false
+		}
+		pingCtx, cancelPing := context.WithTimeout(ctx, prePingTimeout)
+		defer cancelPing()
+		return conn.Ping(pingCtx) == nil
+	}
+}
+
+// wrapConnAcquired wraps the given BeforeAcquire hook in order to flag, in the context returned by
+// withAcquireTracking, that a connection was obtained.
+func wrapConnAcquired(beforeAcquire func(ctx context.Context, conn *pgx.Conn) bool) func(ctx context.Context, conn *pgx.Conn) bool {
+	return func(ctx context.Context, conn *pgx.Conn) bool {
+		if beforeAcquire != nil && !beforeAcquire(ctx, conn) {
+			return false
+		}
+		if acquired, ok := ctx.Value(connAcquiredCtxKey{}).(*atomic.Bool); ok {
+			acquired.Store(true)
+		}
+		return true
+	}
+}
+
+// acquireConn acquires a connection from the pool retrying if the server rejected the connection
+// because there are too many.
+func (db *Database) acquireConn(ctx context.Context) (*pgxpool.Conn, error) {
+	backoff := initialTooManyConnsBackoff
+	for attempt := 0; ; attempt++ {
+		conn, err := db.pool.Load().Acquire(ctx)
+		if err == nil || attempt >= db.tooManyConnsRetries || !IsTooManyConnectionsError(err) {
+			return conn, err
+		}
+
+		// Wait before retrying
+		select {
+		case <-ctx.Done():
+			return nil, err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > maxTooManyConnsBackoff {
+			backoff = maxTooManyConnsBackoff
+		}
+	}
+}
+
+func (db *Database) connectWithRetry(ctx context.Context, retries int, backoff time.Duration) error {
+	for attempt := 0; ; attempt++ {
+		err := db.pool.Load().Ping(ctx)
+		if err == nil {
+			return nil
+		}
+		if attempt >= retries {
+			return newError(err, "unable to connect to the database server")
+		}
+
+		// Wait before retrying
+		select {
+		case <-ctx.Done():
+			return newError(ctx.Err(), "unable to connect to the database server")
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > maxConnectRetryBackoff {
+			backoff = maxConnectRetryBackoff
+		}
+	}
+}
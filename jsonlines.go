@@ -0,0 +1,97 @@
+// See the LICENSE file for license details.
+
+package postgres
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+)
+
+// -----------------------------------------------------------------------------
+
+const (
+	jsonLinesFlushInterval = 100
+)
+
+// -----------------------------------------------------------------------------
+
+// QueryJSONLines executes a SQL query and writes each returned row to the writer as a JSON object
+// followed by a newline (NDJSON). Object keys are the column names in the order they are returned.
+//
+// Rows are streamed to the writer as they are received and the output is flushed periodically. If
+// writing fails, the query is aborted and the error is returned.
+func (db *Database) QueryJSONLines(ctx context.Context, w io.Writer, sql string, args ...interface{}) error {
+	var writeErr error
+
+	ctx = db.withAcquireTracking(ctx)
+
+	// Use a cancellable context to abort the query if the writer fails
+	queryCtx, cancelQuery := context.WithCancel(ctx)
+	defer cancelQuery()
+
+	rows, err := db.pool.Load().Query(queryCtx, sql, db.withQueryExecMode(ctx, sql, args)...)
+	if err != nil {
+		return db.handleError(ctx, db.newAcquireError(ctx, err, "unable to run query"))
+	}
+	defer rows.Close()
+
+	// Pre-encode the column names
+	fields := rows.FieldDescriptions()
+	keys := make([][]byte, len(fields))
+	for idx, fd := range fields {
+		keys[idx], _ = json.Marshal(fd.Name)
+	}
+
+	bw := bufio.NewWriter(w)
+	rowCount := 0
+	for rows.Next() {
+		var values []interface{}
+
+		values, err = rows.Values()
+		if err != nil {
+			cancelQuery()
+			return db.handleError(ctx, newError(err, "unable to scan row"))
+		}
+
+		writeErr = writeJSONLine(bw, keys, values)
+		if writeErr == nil {
+			rowCount += 1
+			if rowCount%jsonLinesFlushInterval == 0 {
+				writeErr = bw.Flush()
+			}
+		}
+		if writeErr != nil {
+			cancelQuery()
+			return writeErr
+		}
+	}
+	err = rows.Err()
+	if err != nil {
+		return db.handleError(ctx, newError(err, "unable to run query"))
+	}
+
+	// Done
+	return bw.Flush()
+}
+
+func writeJSONLine(bw *bufio.Writer, keys [][]byte, values []interface{}) error {
+	_ = bw.WriteByte('{')
+	for idx, value := range values {
+		if idx > 0 {
+			_ = bw.WriteByte(',')
+		}
+		_, _ = bw.Write(keys[idx])
+		_ = bw.WriteByte(':')
+
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		_, _ = bw.Write(encoded)
+	}
+	_ = bw.WriteByte('}')
+	_, err := bw.WriteString("\n")
+	return err
+}
@@ -0,0 +1,90 @@
+// See the LICENSE file for license details.
+
+package postgres
+
+import (
+	"context"
+	"io"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// -----------------------------------------------------------------------------
+
+// LargeObjects provides access to the large objects of the database within a transaction.
+//
+// Unlike BYTEA values, which must be fully loaded in memory because they are sent and received as
+// a single parameter or field, large objects can be streamed in chunks. On the other side, large
+// objects are stored outside the referencing table so they must be explicitly deleted with Unlink
+// and they can only be accessed within a transaction.
+type LargeObjects struct {
+	tx *Tx
+	lo pgx.LargeObjects
+}
+
+// -----------------------------------------------------------------------------
+
+// LargeObjects returns an accessor to the large objects of the database bound to this transaction.
+func (tx *Tx) LargeObjects() *LargeObjects {
+	return &LargeObjects{
+		tx: tx,
+		lo: tx.tx.LargeObjects(),
+	}
+}
+
+// Create creates a new empty large object and returns its OID.
+func (l *LargeObjects) Create(ctx context.Context) (uint32, error) {
+	oid, err := l.lo.Create(ctx, 0)
+	if err != nil {
+		return 0, l.tx.db.handleError(ctx, newError(err, "unable to create large object"))
+	}
+	return oid, nil
+}
+
+// Write streams the content of the reader into the large object with the specified OID replacing
+// its previous content. It returns the number of bytes written.
+func (l *LargeObjects) Write(ctx context.Context, oid uint32, r io.Reader) (int64, error) {
+	obj, err := l.lo.Open(ctx, oid, pgx.LargeObjectModeWrite)
+	if err != nil {
+		return 0, l.tx.db.handleError(ctx, newError(err, "unable to open large object"))
+	}
+
+	err = obj.Truncate(0)
+	if err == nil {
+		var n int64
+
+		n, err = io.Copy(obj, r)
+		if err == nil {
+			err = obj.Close()
+			if err == nil {
+				return n, nil
+			}
+		}
+	}
+	_ = obj.Close()
+	return 0, l.tx.db.handleError(ctx, newError(err, "unable to write large object"))
+}
+
+// Read streams the content of the large object with the specified OID into the writer. It returns
+// the number of bytes read.
+func (l *LargeObjects) Read(ctx context.Context, oid uint32, w io.Writer) (int64, error) {
+	obj, err := l.lo.Open(ctx, oid, pgx.LargeObjectModeRead)
+	if err != nil {
+		return 0, l.tx.db.handleError(ctx, newError(err, "unable to open large object"))
+	}
+
+	n, err := io.Copy(w, obj)
+	_ = obj.Close()
+	if err != nil {
+		return 0, l.tx.db.handleError(ctx, newError(err, "unable to read large object"))
+	}
+
+	// Done
+	return n, nil
+}
+
+// Unlink deletes the large object with the specified OID.
+func (l *LargeObjects) Unlink(ctx context.Context, oid uint32) error {
+	err := l.lo.Unlink(ctx, oid)
+	return l.tx.db.handleError(ctx, newError(err, "unable to delete large object"))
+}
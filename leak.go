@@ -0,0 +1,96 @@
+// See the LICENSE file for license details.
+
+package postgres
+
+import (
+	"context"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// -----------------------------------------------------------------------------
+
+// ConnLeakHandler defines a callback that is called when a connection is held longer than the
+// configured threshold. The stack contains the trace of the goroutine that acquired it.
+type ConnLeakHandler func(held time.Duration, stack []byte)
+
+type connLeakDetector struct {
+	mutex     sync.Mutex
+	threshold time.Duration
+	handler   ConnLeakHandler
+	tracked   map[*pgx.Conn]*time.Timer
+}
+
+// -----------------------------------------------------------------------------
+
+func newConnLeakDetector(threshold time.Duration, handler ConnLeakHandler) *connLeakDetector {
+	return &connLeakDetector{
+		mutex:     sync.Mutex{},
+		threshold: threshold,
+		handler:   handler,
+		tracked:   make(map[*pgx.Conn]*time.Timer),
+	}
+}
+
+func (d *connLeakDetector) wrapBeforeAcquire(
+	beforeAcquire func(ctx context.Context, conn *pgx.Conn) bool,
+) func(ctx context.Context, conn *pgx.Conn) bool {
+	return func(ctx context.Context, conn *pgx.Conn) bool {
+		if beforeAcquire != nil && !beforeAcquire(ctx, conn) {
+			return false
+		}
+
+		// NOTE: The hook is executed by the goroutine acquiring the connection.
+		stack := debug.Stack()
+		acquiredAt := time.Now()
+		timer := time.AfterFunc(d.threshold, func() {
+			d.handler(time.Since(acquiredAt), stack)
+		})
+
+		d.mutex.Lock()
+		if oldTimer, ok := d.tracked[conn]; ok {
+			oldTimer.Stop()
+		}
+		d.tracked[conn] = timer
+		d.mutex.Unlock()
+
+		// Done
+		return true
+	}
+}
+
+func (d *connLeakDetector) wrapAfterRelease(afterRelease func(conn *pgx.Conn) bool) func(conn *pgx.Conn) bool {
+	return func(conn *pgx.Conn) bool {
+		d.untrack(conn)
+
+		if afterRelease != nil {
+			return afterRelease(conn)
+		}
+		return true
+	}
+}
+
+// wrapBeforeClose stops tracking connections destroyed on release, for e.g. because they were
+// closed or expired, because the pool does not call the AfterRelease hook for them.
+func (d *connLeakDetector) wrapBeforeClose(beforeClose func(conn *pgx.Conn)) func(conn *pgx.Conn) {
+	return func(conn *pgx.Conn) {
+		d.untrack(conn)
+
+		if beforeClose != nil {
+			beforeClose(conn)
+		}
+	}
+}
+
+func (d *connLeakDetector) untrack(conn *pgx.Conn) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if timer, ok := d.tracked[conn]; ok {
+		timer.Stop()
+		delete(d.tracked, conn)
+	}
+}
@@ -0,0 +1,51 @@
+// See the LICENSE file for license details.
+
+package postgres_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mxmauro/go-postgres/v2"
+)
+
+// -----------------------------------------------------------------------------
+
+func TestConnLeakDetector(t *testing.T) {
+	var leaks atomic.Int32
+
+	ctx := context.Background()
+
+	threshold := 300 * time.Millisecond
+	db := openTestDatabaseWithOptions(ctx, t, func(opts *postgres.Options) {
+		opts.ConnLeakThreshold = threshold
+		opts.OnConnLeak = func(_ time.Duration, _ []byte) {
+			leaks.Add(1)
+		}
+	})
+	defer db.Close()
+
+	// A connection held longer than the threshold is reported
+	err := db.WithinConn(ctx, func(ctx context.Context, conn *postgres.Conn) error {
+		time.Sleep(2 * threshold)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+	if leaks.Load() != 1 {
+		t.Fatalf("leaked connection was not reported [count=%v]", leaks.Load())
+	}
+
+	// A connection closed while held is destroyed on release and must not be reported
+	_ = db.WithinConn(ctx, func(ctx context.Context, conn *postgres.Conn) error {
+		_, err := conn.Exec(ctx, `SELECT pg_terminate_backend(pg_backend_pid())`)
+		return err
+	})
+	time.Sleep(2 * threshold)
+	if leaks.Load() != 1 {
+		t.Fatalf("closed connection was reported as leaked [count=%v]", leaks.Load())
+	}
+}
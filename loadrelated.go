@@ -0,0 +1,46 @@
+// See the LICENSE file for license details.
+
+package postgres
+
+import (
+	"context"
+)
+
+// -----------------------------------------------------------------------------
+
+// LoadRelatedScanCallback defines a callback that scans a related row and returns the parent key
+// it belongs to along with the scanned item.
+type LoadRelatedScanCallback = func(row Row) (int64, interface{}, error)
+
+// -----------------------------------------------------------------------------
+
+// LoadRelated executes a single query to load the rows related to all the provided parent keys
+// and groups the results by the parent key returned by the scan callback.
+//
+// The query is wrapped like `SELECT * FROM (<sql>) WHERE <keyColumn> = ANY($1)` so it can contain
+// its own filters but must not use positional parameters. Parent keys without related rows are
+// not present in the returned map.
+func (db *Database) LoadRelated(
+	ctx context.Context, parentIDs []int64, sql string, keyColumn string, scan LoadRelatedScanCallback,
+) (map[int64][]interface{}, error) {
+	result := make(map[int64][]interface{})
+	if len(parentIDs) == 0 {
+		return result, nil
+	}
+
+	sql = `SELECT * FROM (` + sql + `) AS _related WHERE ` + QuoteIdentifier(keyColumn) + ` = ANY($1)`
+	err := db.QueryRows(ctx, sql, parentIDs).Do(func(ctx context.Context, row Row) (bool, error) {
+		key, item, err := scan(row)
+		if err != nil {
+			return false, err
+		}
+		result[key] = append(result[key], item)
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Done
+	return result, nil
+}
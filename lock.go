@@ -0,0 +1,68 @@
+// See the LICENSE file for license details.
+
+package postgres
+
+import (
+	"context"
+	"errors"
+)
+
+// -----------------------------------------------------------------------------
+
+// LockOptions defines how LockRow behaves if the row is already locked by another transaction.
+type LockOptions struct {
+	// NoWait makes LockRow fail immediately with an ErrorTypeLockTimeout error instead of waiting.
+	NoWait bool
+
+	// SkipLocked makes LockRow return a NoRowsError immediately instead of waiting.
+	SkipLocked bool
+}
+
+// -----------------------------------------------------------------------------
+
+// LockRow locks the row of the table whose key column matches the given key by executing
+// `SELECT * FROM table WHERE key = $1 FOR UPDATE` within the transaction and returns it. The lock
+// is held until the transaction ends.
+//
+// If the row does not exist, or it is locked and SkipLocked is set, a NoRowsError is returned. If
+// the row is locked and NoWait is set, an ErrorTypeLockTimeout error is returned.
+//
+// The row is read before returning and decoded using the standard PGX type mappings, so custom
+// data types are not supported.
+func (db *Database) LockRow(
+	ctx context.Context, tx *Tx, tableName string, keyColumn string, key interface{}, opts LockOptions,
+) (Row, error) {
+	if opts.NoWait && opts.SkipLocked {
+		return nil, errors.New("NoWait and SkipLocked lock options are mutually exclusive")
+	}
+
+	sql := "SELECT * FROM " + db.quoteTableName(tableName) + " WHERE " + QuoteIdentifier(keyColumn) + " = $1 FOR UPDATE"
+	if opts.NoWait {
+		sql += " NOWAIT"
+	} else if opts.SkipLocked {
+		sql += " SKIP LOCKED"
+	}
+
+	ctx = tx.stats.track(ctx)
+	rows, err := tx.tx.Query(ctx, sql, key)
+	if err != nil {
+		return nil, db.handleError(ctx, newError(err, "unable to lock row"))
+	}
+	result, err := readCachedResult(rows)
+	if err != nil {
+		return nil, db.handleError(ctx, newError(err, "unable to lock row"))
+	}
+	if len(result.Rows) == 0 {
+		return nil, db.handleError(ctx, errNoRows)
+	}
+
+	// Done
+	return &cachedRow{
+		rows: &cachedRows{
+			ctx:    ctx,
+			db:     db,
+			result: result,
+		},
+		values: result.Rows[0],
+	}, nil
+}
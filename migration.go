@@ -5,14 +5,22 @@ package postgres
 import (
 	"context"
 	"errors"
+	"fmt"
 	"hash/fnv"
 	"math"
 	"strings"
+	"time"
 	"unicode/utf8"
 )
 
 // -----------------------------------------------------------------------------
 
+const (
+	migrationLockRetryInterval = 500 * time.Millisecond
+)
+
+// -----------------------------------------------------------------------------
+
 // MigrationStep contains details about the SQL sentence to execute in this step.
 // Pass an empty struct to indicate the end.
 type MigrationStep struct {
@@ -24,11 +32,77 @@ type MigrationStep struct {
 
 	// Actual SQL sentence to execute in this migration step.
 	Sql string
+
+	// Additional SQL sentences to execute, in order, after Sql. All the sentences of a step are
+	// executed within the same transaction so the step remains atomic.
+	Sqls []string
+
+	// NoTransaction executes the step outside a transaction. Required by commands that cannot run
+	// inside a transaction block like CREATE INDEX CONCURRENTLY.
+	//
+	// NOTE: Such steps are not atomic. If a sentence fails, the previous ones are not rolled back and
+	//       the step is not recorded as applied, so they must be written to be safely re-executed.
+	NoTransaction bool
 }
 
 // MigrationStepCallback is called to get the migration step details at stepIdx position (starting from 1)
 type MigrationStepCallback func(ctx context.Context, stepIdx int) (MigrationStep, error)
 
+// MigrationParserOptions defines options used while parsing migration steps from SQL content.
+type MigrationParserOptions struct {
+	// BlockMarker is the prefix of the lines that start a new named block. Defaults to "#".
+	// I.e.: Use "-- name:" to parse headers like "-- name: v1->v2".
+	BlockMarker string
+}
+
+// MigrationOptions defines additional options used while running migrations.
+type MigrationOptions struct {
+	// StatementTimeout sets the maximum execution time of each SQL sentence of a step. Zero means no limit.
+	StatementTimeout time.Duration
+
+	// LockTimeout sets the maximum time a SQL sentence of a step can wait to acquire a lock. Zero means
+	// no limit.
+	LockTimeout time.Duration
+
+	// MigrationLockTimeout sets the maximum time to wait for other instances running migrations to
+	// finish. Zero means wait forever.
+	MigrationLockTimeout time.Duration
+
+	// OnProgress, if set, is called to report the progress of each migration step.
+	OnProgress MigrationProgressCallback
+}
+
+// MigrationOutcome indicates the stage of a migration step reported to the progress callback.
+type MigrationOutcome int
+
+const (
+	MigrationOutcomeStarting MigrationOutcome = iota
+	MigrationOutcomeApplied
+	MigrationOutcomeSkipped
+	MigrationOutcomeFailed
+)
+
+// MigrationProgress contains details about the progress of a migration step.
+type MigrationProgress struct {
+	// Name and SequenceNo of the step.
+	Name       string
+	SequenceNo int
+
+	// StepIdx is the position of the step starting from 1.
+	StepIdx int
+
+	Outcome MigrationOutcome
+
+	// Duration contains the execution time of applied and failed steps.
+	Duration time.Duration
+
+	// Err contains the error of failed steps.
+	Err error
+}
+
+// MigrationProgressCallback defines a callback called to report the progress of a migration step.
+type MigrationProgressCallback func(progress MigrationProgress)
+
 // -----------------------------------------------------------------------------
 
 // CreateMigrationStepsFromSqlContent creates an array of migration steps based on the provided content
@@ -37,11 +111,30 @@ type MigrationStepCallback func(ctx context.Context, stepIdx int) (MigrationStep
 // # a comment with the step name (starting and ending spaces and dashes will be removed)
 // A single SQL sentence
 // (extra comment/sql sentence pairs)
+//
+// A `# @no-transaction` (or `-- @no-transaction`) line after the step name marks the sentences of
+// that block to be executed outside a transaction.
+//
+// Standard SQL comments (`-- ...` and `/* ... */`) are skipped both between and inside sentences.
 func CreateMigrationStepsFromSqlContent(content string) ([]MigrationStep, error) {
+	return CreateMigrationStepsFromSqlContentWithOptions(content, MigrationParserOptions{})
+}
+
+// CreateMigrationStepsFromSqlContentWithOptions creates an array of migration steps based on the
+// provided content like CreateMigrationStepsFromSqlContent does but allows to customize the marker
+// used to start a named block. When a custom marker is used, lines starting with `#` are treated as
+// ordinary comments.
+func CreateMigrationStepsFromSqlContentWithOptions(content string, opts MigrationParserOptions) ([]MigrationStep, error) {
 	steps := make([]MigrationStep, 0)
 
+	blockMarker := opts.BlockMarker
+	if len(blockMarker) == 0 {
+		blockMarker = "#"
+	}
+
 	currentName := ""
 	currentSeqNo := 1
+	currentNoTx := false
 
 	// Parse content
 	contentLen := len(content)
@@ -53,21 +146,63 @@ func CreateMigrationStepsFromSqlContent(content string) ([]MigrationStep, error)
 			continue
 		}
 
-		// Is it a comment at the beginning of the line?
-		if content[ofs] == '#' {
+		// Is it a block marker at the beginning of the line?
+		if strings.HasPrefix(content[ofs:], blockMarker) {
 			// Yes, assume new zone if we are not in the middle of an sql sentence
-			startOfs := ofs
+			startOfs := ofs + len(blockMarker)
 			ofs += findEol(content[ofs:])
 
-			currentName = truncStrBytes(strings.Trim(content[startOfs:ofs], " \t-=#"), 255)
+			name := strings.Trim(content[startOfs:ofs], " \t-=#")
+			if isNoTransactionDirective(name) {
+				if len(currentName) == 0 {
+					return nil, errors.New("directive found outside a block")
+				}
+				currentNoTx = true
+				continue
+			}
+
+			currentName = truncStrBytes(name, 255)
 			if len(currentName) == 0 {
 				return nil, errors.New("empty start of block comment")
 			}
 			currentSeqNo = 1
+			currentNoTx = false
 
 			continue
 		}
 
+		// Skip SQL comments between sentences
+		spacesOfs := skipSpaces(content[ofs:])
+		commentLen, err := skipSqlComment(content[ofs+spacesOfs:])
+		if err != nil {
+			return nil, err
+		}
+		if commentLen > 0 {
+			// A directive can also be specified with an SQL comment
+			if isNoTransactionDirective(content[ofs+spacesOfs : ofs+spacesOfs+commentLen]) {
+				if len(currentName) == 0 {
+					return nil, errors.New("directive found outside a block")
+				}
+				currentNoTx = true
+			}
+
+			ofs += spacesOfs + commentLen
+			continue
+		}
+
+		// Is it an ordinary comment at the beginning of the line?
+		if content[ofs] == '#' {
+			if isNoTransactionDirective(content[ofs : ofs+findEol(content[ofs:])]) {
+				if len(currentName) == 0 {
+					return nil, errors.New("directive found outside a block")
+				}
+				currentNoTx = true
+			}
+
+			ofs += findEol(content[ofs:])
+			continue
+		}
+
 		// At this point we start to parse an SQL sentence
 		if len(currentName) == 0 {
 			return nil, errors.New("SQL sentence found outside a block")
@@ -89,6 +224,17 @@ func CreateMigrationStepsFromSqlContent(content string) ([]MigrationStep, error)
 				continue
 			}
 
+			deltaOfs, err = skipSqlComment(content[ofs:])
+			if err != nil {
+				return nil, err
+			}
+			if deltaOfs > 0 {
+				// We find an SQL comment, skip it
+				addSpace = true
+				ofs += deltaOfs
+				continue
+			}
+
 			if content[ofs] == '#' {
 				// We find a comment, skip until EOL
 				addSpace = true
@@ -103,9 +249,10 @@ func CreateMigrationStepsFromSqlContent(content string) ([]MigrationStep, error)
 					currentSql.WriteRune(';')
 
 					steps = append(steps, MigrationStep{
-						Name:       currentName,
-						SequenceNo: currentSeqNo,
-						Sql:        currentSql.String(),
+						Name:          currentName,
+						SequenceNo:    currentSeqNo,
+						Sql:           currentSql.String(),
+						NoTransaction: currentNoTx,
 					})
 
 					// Reset
@@ -118,6 +265,53 @@ func CreateMigrationStepsFromSqlContent(content string) ([]MigrationStep, error)
 				break
 			}
 
+			if (content[ofs] == 'E' || content[ofs] == 'e') && ofs+1 < contentLen && content[ofs+1] == '\'' &&
+				(addSpace || !endsWithIdentifierChar(currentSql.String())) {
+				// Start of an escape string
+				startOfs := ofs
+				ofs += 2
+
+				escapedCharacter := false
+				for {
+					if ofs >= contentLen {
+						// Open string found
+						return nil, errors.New("invalid SQL content (open string)")
+					}
+
+					r, rSize := utf8.DecodeRuneInString(content[ofs:])
+					if r == utf8.RuneError || rSize == 0 {
+						return nil, errors.New("invalid SQL content (invalid char)")
+					}
+					ofs += rSize
+
+					if escapedCharacter {
+						escapedCharacter = false
+						continue
+					}
+
+					// Reached the end of the string or double single-quotes?
+					if r == '\'' {
+						if ofs >= contentLen || content[ofs] != '\'' {
+							break // End of string
+						}
+						// Double single-quotes
+						ofs += 1
+					}
+
+					// Escaped character?
+					if r == '\\' {
+						escapedCharacter = true
+					}
+				}
+
+				if addSpace {
+					currentSql.WriteRune(' ')
+					addSpace = false
+				}
+				currentSql.WriteString(content[startOfs:ofs])
+				continue
+			}
+
 			if content[ofs] == '\'' {
 				// Start of a single-quote string
 				startOfs := ofs
@@ -251,9 +445,10 @@ func CreateMigrationStepsFromSqlContent(content string) ([]MigrationStep, error)
 			currentSql.WriteRune(';')
 
 			steps = append(steps, MigrationStep{
-				Name:       currentName,
-				SequenceNo: currentSeqNo,
-				Sql:        currentSql.String(),
+				Name:          currentName,
+				SequenceNo:    currentSeqNo,
+				Sql:           currentSql.String(),
+				NoTransaction: currentNoTx,
 			})
 
 			// Reset
@@ -268,47 +463,62 @@ func CreateMigrationStepsFromSqlContent(content string) ([]MigrationStep, error)
 
 // -----------------------------------------------------------------------------
 
-func (db *Database) RunMigrations(ctx context.Context, tableName string, cb MigrationStepCallback) error {
+// RunMigrations executes the migration steps returned by the callback that were not applied yet.
+//
+// If a step exceeds the configured timeouts, the migration is aborted and the advisory lock that
+// prevents concurrent migrations is released.
+func (db *Database) RunMigrations(
+	ctx context.Context, tableName string, cb MigrationStepCallback, opts ...MigrationOptions,
+) error {
+	var migOpts MigrationOptions
+
+	if len(opts) > 0 {
+		migOpts = opts[0]
+	}
+
 	// Lock concurrent access from multiple instances/threads
 	lockId := db.getMigrationLockId(tableName)
 
 	// Quote table name
-	tableName = quoteIdentifier(tableName)
+	tableName = QuoteIdentifier(tableName)
 
 	// We must execute migrations within a single connection
-	return db.WithinConn(ctx, func(ctx context.Context, conn Conn) error {
+	return db.WithinConn(ctx, func(ctx context.Context, conn *Conn) error {
 		var stepIdx int32
 
-		_, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1)", lockId)
+		err := acquireMigrationLock(ctx, conn, lockId, migOpts.MigrationLockTimeout)
 		if err != nil {
 			return err
 		}
 		defer func() {
-			_, _ = conn.Exec(ctx, "SELECT pg_advisory_unlock($1)", lockId)
+			_, _ = conn.Exec(context.Background(), "SELECT pg_advisory_unlock($1)", lockId) // Using context.Background() on purpose
 		}()
 
-		// Create migration table if it does not exist
-		_, err = conn.Exec(ctx,
-			`CREATE TABLE IF NOT EXISTS `+tableName+` (
-				id         int NOT NULL PRIMARY KEY,
-				name       varchar(255) NOT NULL,
-				sequence   int NOT NULL,
-				executedAt timestamp NOT NULL
-		)`)
+		// Create migration table if it does not exist and calculate the next step index to execute
+		stepIdx, err = initMigrationTable(ctx, conn, tableName)
 		if err != nil {
 			return err
 		}
 
-		// Calculate the next step index to execute based on the last stored
-		row := conn.QueryRow(ctx, `SELECT id FROM `+tableName+` ORDER BY id DESC LIMIT 1`)
-		err = row.Scan(&stepIdx)
-		if err == nil {
-			stepIdx += 1
-		} else {
-			if !IsNoRowsError(err) {
-				return err
+		// Report already applied steps
+		if migOpts.OnProgress != nil {
+			for idx := 1; idx < int(stepIdx); idx++ {
+				var stepInfo MigrationStep
+
+				stepInfo, err = cb(ctx, idx)
+				if err != nil {
+					return err
+				}
+				if len(stepInfo.Name) == 0 {
+					break
+				}
+				migOpts.OnProgress(MigrationProgress{
+					Name:       stepInfo.Name,
+					SequenceNo: stepInfo.SequenceNo,
+					StepIdx:    idx,
+					Outcome:    MigrationOutcomeSkipped,
+				})
 			}
-			stepIdx = 1
 		}
 
 		// Run migrations
@@ -325,10 +535,20 @@ func (db *Database) RunMigrations(ctx context.Context, tableName string, cb Migr
 			}
 
 			// Execute step
-			err = conn.WithinTx(ctx, func(ctx context.Context, tx Tx) error {
-				_, stepErr := tx.Exec(ctx, stepInfo.Sql)
+			runStep := func(ctx context.Context, q ExecerQueryer) error {
+				var stepErr error
+
+				if !stepInfo.NoTransaction {
+					stepErr = setMigrationTimeouts(ctx, q, "SET LOCAL", migOpts)
+				}
+				if stepErr == nil && len(stepInfo.Sql) > 0 {
+					_, stepErr = q.Exec(ctx, stepInfo.Sql)
+				}
+				for idx := 0; stepErr == nil && idx < len(stepInfo.Sqls); idx++ {
+					_, stepErr = q.Exec(ctx, stepInfo.Sqls[idx])
+				}
 				if stepErr == nil {
-					_, stepErr = tx.Exec(
+					_, stepErr = q.Exec(
 						ctx,
 						`INSERT INTO `+tableName+` (id, name, sequence, executedAt) VALUES ($1, $2, $3, NOW());`,
 						stepIdx, stepInfo.Name, stepInfo.SequenceNo,
@@ -336,7 +556,42 @@ func (db *Database) RunMigrations(ctx context.Context, tableName string, cb Migr
 				}
 				// Done
 				return stepErr
-			})
+			}
+			progress := MigrationProgress{
+				Name:       stepInfo.Name,
+				SequenceNo: stepInfo.SequenceNo,
+				StepIdx:    int(stepIdx),
+				Outcome:    MigrationOutcomeStarting,
+			}
+			if migOpts.OnProgress != nil {
+				migOpts.OnProgress(progress)
+			}
+			startTime := time.Now()
+
+			if stepInfo.NoTransaction {
+				err = setMigrationTimeouts(ctx, conn, "SET", migOpts)
+				if err == nil {
+					err = runStep(ctx, conn)
+					resetMigrationTimeouts(context.Background(), conn, migOpts) // Using context.Background() on purpose
+				}
+			} else {
+				err = conn.WithinTx(ctx, func(ctx context.Context, tx *Tx) error {
+					return runStep(ctx, tx)
+				})
+			}
+			if err != nil {
+				err = newMigrationStepError(err, int(stepIdx), stepInfo)
+			}
+			if migOpts.OnProgress != nil {
+				progress.Duration = time.Since(startTime)
+				if err == nil {
+					progress.Outcome = MigrationOutcomeApplied
+				} else {
+					progress.Outcome = MigrationOutcomeFailed
+					progress.Err = err
+				}
+				migOpts.OnProgress(progress)
+			}
 			if err != nil {
 				return err
 			}
@@ -350,6 +605,228 @@ func (db *Database) RunMigrations(ctx context.Context, tableName string, cb Migr
 	})
 }
 
+// BaselineMigrations marks the migration steps from 1 to upToIndex as applied without executing
+// them. Use it when adopting migrations on an existing database whose schema is already up to date.
+// Subsequent calls to RunMigrations continue from upToIndex+1.
+//
+// The callback is used to retrieve the name and sequence of each step. Steps already marked as
+// applied are left untouched.
+func (db *Database) BaselineMigrations(
+	ctx context.Context, tableName string, upToIndex int, cb MigrationStepCallback,
+) error {
+	// Lock concurrent access from multiple instances/threads
+	lockId := db.getMigrationLockId(tableName)
+
+	// Quote table name
+	tableName = QuoteIdentifier(tableName)
+
+	return db.WithinConn(ctx, func(ctx context.Context, conn *Conn) error {
+		err := acquireMigrationLock(ctx, conn, lockId, 0)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			_, _ = conn.Exec(context.Background(), "SELECT pg_advisory_unlock($1)", lockId) // Using context.Background() on purpose
+		}()
+
+		// Create migration table if it does not exist and calculate the next step index to record
+		stepIdx, err := initMigrationTable(ctx, conn, tableName)
+		if err != nil {
+			return err
+		}
+
+		// Record steps
+		return conn.WithinTx(ctx, func(ctx context.Context, tx *Tx) error {
+			for ; int(stepIdx) <= upToIndex; stepIdx++ {
+				stepInfo, err := cb(ctx, int(stepIdx))
+				if err != nil {
+					return err
+				}
+				if len(stepInfo.Name) == 0 {
+					return fmt.Errorf("migration step #%d not found", stepIdx)
+				}
+
+				_, err = tx.Exec(
+					ctx,
+					`INSERT INTO `+tableName+` (id, name, sequence, executedAt) VALUES ($1, $2, $3, NOW());`,
+					stepIdx, stepInfo.Name, stepInfo.SequenceNo,
+				)
+				if err != nil {
+					return err
+				}
+			}
+
+			// Done
+			return nil
+		})
+	})
+}
+
+// VerifyMigrations checks the steps stored in the migrations table form a contiguous sequence
+// starting from 1 and each stored name and sequence number matches the step returned by the
+// callback for the same index. A detailed error is returned on the first mismatch.
+func (db *Database) VerifyMigrations(ctx context.Context, tableName string, cb MigrationStepCallback) error {
+	var exists bool
+
+	// Quote table name
+	tableName = QuoteIdentifier(tableName)
+
+	err := db.QueryRow(ctx, `SELECT to_regclass($1) IS NOT NULL`, tableName).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return nil // Nothing was applied yet
+	}
+
+	expectedStepIdx := 1
+	return db.QueryRows(ctx, `SELECT id, name, sequence FROM `+tableName+` ORDER BY id`).Do(
+		func(ctx context.Context, row Row) (bool, error) {
+			var stepIdx int
+			var name string
+			var sequenceNo int
+
+			err := row.Scan(&stepIdx, &name, &sequenceNo)
+			if err != nil {
+				return false, err
+			}
+			if stepIdx != expectedStepIdx {
+				return false, fmt.Errorf("migration step #%d is missing [found=%d]", expectedStepIdx, stepIdx)
+			}
+
+			stepInfo, err := cb(ctx, stepIdx)
+			if err != nil {
+				return false, err
+			}
+			if len(stepInfo.Name) == 0 {
+				return false, fmt.Errorf("applied migration step #%d is unknown [name=%s/sequence=%d]",
+					stepIdx, name, sequenceNo)
+			}
+			if stepInfo.Name != name || stepInfo.SequenceNo != sequenceNo {
+				return false, fmt.Errorf(
+					"migration step #%d mismatch [stored=%s/%d] [expected=%s/%d]",
+					stepIdx, name, sequenceNo, stepInfo.Name, stepInfo.SequenceNo,
+				)
+			}
+
+			expectedStepIdx += 1
+			return true, nil
+		},
+	)
+}
+
+func initMigrationTable(ctx context.Context, conn *Conn, tableName string) (int32, error) {
+	var stepIdx int32
+
+	_, err := conn.Exec(ctx,
+		`CREATE TABLE IF NOT EXISTS `+tableName+` (
+			id         int NOT NULL PRIMARY KEY,
+			name       varchar(255) NOT NULL,
+			sequence   int NOT NULL,
+			executedAt timestamp NOT NULL
+	)`)
+	if err != nil {
+		return 0, err
+	}
+
+	// Calculate the next step index based on the last stored
+	row := conn.QueryRow(ctx, `SELECT id FROM `+tableName+` ORDER BY id DESC LIMIT 1`)
+	err = row.Scan(&stepIdx)
+	if err == nil {
+		stepIdx += 1
+	} else {
+		if !IsNoRowsError(err) {
+			return 0, err
+		}
+		stepIdx = 1
+	}
+
+	// Done
+	return stepIdx, nil
+}
+
+func acquireMigrationLock(ctx context.Context, conn *Conn, lockId int64, timeout time.Duration) error {
+	if timeout <= 0 {
+		_, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1)", lockId)
+		return err
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		var locked bool
+
+		err := conn.QueryRow(ctx, "SELECT pg_try_advisory_lock($1)", lockId).Scan(&locked)
+		if err != nil {
+			return err
+		}
+		if locked {
+			return nil
+		}
+
+		// Wait a bit before retrying
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return fmt.Errorf("could not acquire migration lock within %v", timeout)
+		}
+		if remaining > migrationLockRetryInterval {
+			remaining = migrationLockRetryInterval
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(remaining):
+		}
+	}
+}
+
+func setMigrationTimeouts(ctx context.Context, q ExecerQueryer, setCmd string, opts MigrationOptions) error {
+	if opts.StatementTimeout > 0 {
+		_, err := q.Exec(ctx, setCmd+" statement_timeout = "+formatTimeoutParam(opts.StatementTimeout))
+		if err != nil {
+			return err
+		}
+	}
+	if opts.LockTimeout > 0 {
+		_, err := q.Exec(ctx, setCmd+" lock_timeout = "+formatTimeoutParam(opts.LockTimeout))
+		if err != nil {
+			return err
+		}
+	}
+
+	// Done
+	return nil
+}
+
+func resetMigrationTimeouts(ctx context.Context, q ExecerQueryer, opts MigrationOptions) {
+	if opts.StatementTimeout > 0 {
+		_, _ = q.Exec(ctx, "RESET statement_timeout")
+	}
+	if opts.LockTimeout > 0 {
+		_, _ = q.Exec(ctx, "RESET lock_timeout")
+	}
+}
+
+func newMigrationStepError(err error, stepIdx int, stepInfo MigrationStep) error {
+	var e *Error
+
+	if errors.As(err, &e) && e.Details != nil {
+		switch e.Details.Code {
+		case "57014": // query_canceled
+			fallthrough
+		case "55P03": // lock_not_available
+			return &Error{
+				message: fmt.Sprintf("migration step timed out [index=%d/name=%s/sequence=%d]",
+					stepIdx, stepInfo.Name, stepInfo.SequenceNo),
+				err:            e.err,
+				Details:        e.Details,
+				Type:           e.Type,
+				ConstraintKind: e.ConstraintKind,
+			}
+		}
+	}
+	return err
+}
+
 func (db *Database) getMigrationLockId(tableName string) int64 {
 	h := fnv.New64a()
 	_, _ = h.Write(db.nameHash[:])
@@ -400,6 +877,48 @@ func shouldIgnoreLine(s string) int {
 	return 0
 }
 
+// This function returns the length of the SQL comment at the start of the string or zero if there
+// is no comment.
+func skipSqlComment(s string) (int, error) {
+	if strings.HasPrefix(s, "--") {
+		return findEol(s), nil
+	}
+	if !strings.HasPrefix(s, "/*") {
+		return 0, nil
+	}
+
+	// Block comments can be nested
+	depth := 0
+	for ofs := 0; ofs < len(s); {
+		if strings.HasPrefix(s[ofs:], "/*") {
+			depth += 1
+			ofs += 2
+		} else if strings.HasPrefix(s[ofs:], "*/") {
+			depth -= 1
+			ofs += 2
+			if depth == 0 {
+				return ofs, nil
+			}
+		} else {
+			ofs += 1
+		}
+	}
+	return 0, errors.New("invalid SQL content (open comment)")
+}
+
+func endsWithIdentifierChar(s string) bool {
+	if len(s) == 0 {
+		return false
+	}
+	ch := s[len(s)-1]
+	return ch == '_' || ch == '$' || (ch >= '0' && ch <= '9') || (ch >= 'A' && ch <= 'Z') || (ch >= 'a' && ch <= 'z') ||
+		ch >= 0x80
+}
+
+func isNoTransactionDirective(comment string) bool {
+	return strings.Trim(comment, " \t-=#/*") == "@no-transaction"
+}
+
 func findEol(s string) int {
 	eolOfs := strings.IndexByte(s, '\n')
 	if eolOfs < 0 {
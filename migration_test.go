@@ -4,9 +4,12 @@ package postgres_test
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/mxmauro/go-postgres/v2"
 )
@@ -45,6 +48,24 @@ func TestMigration(t *testing.T) {
 	if err != nil {
 		t.Fatal(err.Error())
 	}
+
+	// t.Log("Run baseline migration test")
+	err = runBaselineMigrationTest(ctx, db)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// t.Log("Run migration timeouts test")
+	err = runMigrationTimeoutsTest(ctx, db)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// t.Log("Run migration lock test")
+	err = runMigrationLockTest(ctx, db)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
 }
 
 func TestMigrationStepParser(t *testing.T) {
@@ -94,6 +115,115 @@ $tag$ LANGUAGE plpgsql;
 	}
 }
 
+func TestMigrationStepParserNoTransaction(t *testing.T) {
+	steps, err := postgres.CreateMigrationStepsFromSqlContent(`
+# Create table
+CREATE TABLE "Employee" ("EmployeeID" SERIAL PRIMARY KEY, "Name" VARCHAR(100) NOT NULL);
+
+# Create index
+# @no-transaction
+CREATE INDEX CONCURRENTLY "idx_employee_name" ON "Employee" ("Name");
+`)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(steps) != 2 {
+		t.Fatalf("Wrong number of steps: %d", len(steps))
+	}
+	if steps[0].NoTransaction || !steps[1].NoTransaction {
+		t.Fatalf("Wrong transaction flags")
+	}
+	if steps[1].Name != "Create index" {
+		t.Fatalf("Wrong step name: %v", steps[1].Name)
+	}
+}
+
+func TestMigrationStepParserSqlComments(t *testing.T) {
+	steps, err := postgres.CreateMigrationStepsFromSqlContent(`
+# Create table
+-- A line comment between sentences
+/* A block comment
+# that spans multiple lines; and contains a semicolon */
+CREATE TABLE "Employee" (
+	"EmployeeID" SERIAL PRIMARY KEY, -- the primary key; with a semicolon
+	/* nested /* block */ comment */ "Name" VARCHAR(100) NOT NULL
+);
+-- Another comment; with a semicolon
+CREATE INDEX "idx_employee_name" ON "Employee" ("Name"); /* trailing */
+`)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(steps) != 2 {
+		t.Fatalf("Wrong number of steps: %d", len(steps))
+	}
+	if steps[0].Sql != `CREATE TABLE "Employee" ( "EmployeeID" SERIAL PRIMARY KEY, "Name" VARCHAR(100) NOT NULL );` {
+		t.Fatalf("Wrong SQL sentence: %v", steps[0].Sql)
+	}
+	if steps[1].Sql != `CREATE INDEX "idx_employee_name" ON "Employee" ("Name");` {
+		t.Fatalf("Wrong SQL sentence: %v", steps[1].Sql)
+	}
+
+	_, err = postgres.CreateMigrationStepsFromSqlContent(`
+# Open comment
+CREATE TABLE "Employee" ("EmployeeID" SERIAL PRIMARY KEY); /* not closed
+`)
+	if err == nil {
+		t.Fatal("Open block comment was accepted")
+	}
+}
+
+func TestMigrationStepParserBlockMarker(t *testing.T) {
+	steps, err := postgres.CreateMigrationStepsFromSqlContentWithOptions(`
+-- name: v1
+-- An ordinary comment
+# Another ordinary comment
+CREATE TABLE "Employee" ("EmployeeID" SERIAL PRIMARY KEY, "Name" VARCHAR(100) NOT NULL);
+
+-- name: v2
+-- @no-transaction
+CREATE INDEX CONCURRENTLY "idx_employee_name" ON "Employee" ("Name");
+`, postgres.MigrationParserOptions{
+		BlockMarker: "-- name:",
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(steps) != 2 {
+		t.Fatalf("Wrong number of steps: %d", len(steps))
+	}
+	if steps[0].Name != "v1" || steps[1].Name != "v2" {
+		t.Fatalf("Wrong step names: %v / %v", steps[0].Name, steps[1].Name)
+	}
+	if steps[0].NoTransaction || !steps[1].NoTransaction {
+		t.Fatalf("Wrong transaction flags")
+	}
+}
+
+func TestMigrationStepParserEscapeStrings(t *testing.T) {
+	steps, err := postgres.CreateMigrationStepsFromSqlContent(`
+# Escape strings
+INSERT INTO "Messages" ("Text") VALUES (E'it\'s; a test'), (e'back\\slash;'), (E'doubled''quote;');
+INSERT INTO "Messages" ("Text") VALUES (E'\'');
+SELECT 'E' AS "Name" FROM "Messages" WHERE "NAME"='a\';
+`)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(steps) != 3 {
+		t.Fatalf("Wrong number of steps: %d", len(steps))
+	}
+	if steps[0].Sql != `INSERT INTO "Messages" ("Text") VALUES (E'it\'s; a test'), (e'back\\slash;'), (E'doubled''quote;');` {
+		t.Fatalf("Wrong SQL sentence: %v", steps[0].Sql)
+	}
+	if steps[1].Sql != `INSERT INTO "Messages" ("Text") VALUES (E'\'');` {
+		t.Fatalf("Wrong SQL sentence: %v", steps[1].Sql)
+	}
+	if steps[2].Sql != `SELECT 'E' AS "Name" FROM "Messages" WHERE "NAME"='a\';` {
+		t.Fatalf("Wrong SQL sentence: %v", steps[2].Sql)
+	}
+}
+
 // -----------------------------------------------------------------------------
 
 func runMigrationTest(ctx context.Context, db *postgres.Database) error {
@@ -124,6 +254,16 @@ func runMigrationTest(ctx context.Context, db *postgres.Database) error {
 				SequenceNo: 2,
 				Sql:        `ALTER TABLE migrations_test ADD COLUMN description TEXT;`,
 			}, nil
+
+		case 3:
+			return postgres.MigrationStep{
+				Name:       "v2",
+				SequenceNo: 1,
+				Sqls: []string{
+					`ALTER TABLE migrations_test ADD COLUMN notes TEXT;`,
+					`CREATE INDEX migrations_test_name_idx ON migrations_test (name);`,
+				},
+			}, nil
 		}
 		return postgres.MigrationStep{}, nil
 	})
@@ -137,19 +277,174 @@ func runMigrationTest(ctx context.Context, db *postgres.Database) error {
 	if err != nil {
 		return fmt.Errorf("unable to get last migration step [err=%v]", err.Error())
 	}
-	if stepIdx != 2 {
-		return fmt.Errorf("last migration step mismatch [got=%v] [expected=2]", stepIdx)
+	if stepIdx != 3 {
+		return fmt.Errorf("last migration step mismatch [got=%v] [expected=3]", stepIdx)
 	}
 
 	// Run more migrations
 	err = db.RunMigrations(ctx, "migrations", func(ctx context.Context, stepIdx int) (postgres.MigrationStep, error) {
+		if stepIdx != 4 {
+			return postgres.MigrationStep{}, fmt.Errorf("migration step mismatch [got=%v] [expected=4]", stepIdx)
+		}
+		return postgres.MigrationStep{}, nil
+	})
+	if err != nil {
+		return fmt.Errorf("unable to run more migrations [err=%v]", err.Error())
+	}
+
+	// Done
+	return nil
+}
+
+func runBaselineMigrationTest(ctx context.Context, db *postgres.Database) error {
+	// Destroy old test table if exists
+	_, err := db.Exec(ctx, `DROP TABLE IF EXISTS migrations_baseline`)
+	if err != nil {
+		return fmt.Errorf("unable to drop table [err=%v]", err.Error())
+	}
+
+	// Mark the first two steps as applied
+	err = db.BaselineMigrations(ctx, "migrations_baseline", 2, func(ctx context.Context, stepIdx int) (postgres.MigrationStep, error) {
+		return postgres.MigrationStep{
+			Name:       "v1",
+			SequenceNo: stepIdx,
+			Sql:        `SELECT invalid_sentence;`,
+		}, nil
+	})
+	if err != nil {
+		return fmt.Errorf("unable to baseline migrations [err=%v]", err.Error())
+	}
+
+	// Run migrations, they must continue from the third step
+	err = db.RunMigrations(ctx, "migrations_baseline", func(ctx context.Context, stepIdx int) (postgres.MigrationStep, error) {
 		if stepIdx != 3 {
 			return postgres.MigrationStep{}, fmt.Errorf("migration step mismatch [got=%v] [expected=3]", stepIdx)
 		}
 		return postgres.MigrationStep{}, nil
 	})
 	if err != nil {
-		return fmt.Errorf("unable to run more migrations [err=%v]", err.Error())
+		return fmt.Errorf("unable to run migrations after baseline [err=%v]", err.Error())
+	}
+
+	// Verify stored steps
+	err = db.VerifyMigrations(ctx, "migrations_baseline", func(ctx context.Context, stepIdx int) (postgres.MigrationStep, error) {
+		return postgres.MigrationStep{
+			Name:       "v1",
+			SequenceNo: stepIdx,
+		}, nil
+	})
+	if err != nil {
+		return fmt.Errorf("unable to verify migrations [err=%v]", err.Error())
+	}
+	err = db.VerifyMigrations(ctx, "migrations_baseline", func(ctx context.Context, stepIdx int) (postgres.MigrationStep, error) {
+		return postgres.MigrationStep{
+			Name:       "v2",
+			SequenceNo: stepIdx,
+		}, nil
+	})
+	if err == nil {
+		return errors.New("migration verification succeeded with mismatched steps")
+	}
+
+	// Done
+	return nil
+}
+
+func runMigrationTimeoutsTest(ctx context.Context, db *postgres.Database) error {
+	// Destroy old test table if exists
+	_, err := db.Exec(ctx, `DROP TABLE IF EXISTS migrations_timeouts`)
+	if err != nil {
+		return fmt.Errorf("unable to drop table [err=%v]", err.Error())
+	}
+	defer func() {
+		_, _ = db.Exec(ctx, `DROP TABLE IF EXISTS migrations_timeouts`)
+	}()
+
+	// A sub-millisecond timeout must be rounded up instead of disabling it
+	for _, noTx := range []bool{false, true} {
+		err = db.RunMigrations(ctx, "migrations_timeouts", func(ctx context.Context, stepIdx int) (postgres.MigrationStep, error) {
+			if stepIdx != 1 {
+				return postgres.MigrationStep{}, nil
+			}
+			return postgres.MigrationStep{
+				Name:          "v1",
+				SequenceNo:    1,
+				Sql:           `SELECT pg_sleep(0.5);`,
+				NoTransaction: noTx,
+			}, nil
+		}, postgres.MigrationOptions{
+			StatementTimeout: 500 * time.Microsecond,
+		})
+		if err == nil {
+			return fmt.Errorf("migration step did not time out [noTransaction=%v]", noTx)
+		}
+		if !strings.Contains(err.Error(), "timed out") {
+			return fmt.Errorf("unexpected migration error [noTransaction=%v/err=%v]", noTx, err.Error())
+		}
+	}
+
+	// Done
+	return nil
+}
+
+func runMigrationLockTest(ctx context.Context, db *postgres.Database) error {
+	// Destroy old test table if exists
+	_, err := db.Exec(ctx, `DROP TABLE IF EXISTS migrations_lock`)
+	if err != nil {
+		return fmt.Errorf("unable to drop table [err=%v]", err.Error())
+	}
+	defer func() {
+		_, _ = db.Exec(ctx, `DROP TABLE IF EXISTS migrations_lock`)
+	}()
+
+	// Run a slow migration that holds the lock
+	started := make(chan struct{})
+	slowErrCh := make(chan error, 1)
+	go func() {
+		slowErrCh <- db.RunMigrations(ctx, "migrations_lock", func(ctx context.Context, stepIdx int) (postgres.MigrationStep, error) {
+			if stepIdx != 1 {
+				return postgres.MigrationStep{}, nil
+			}
+			close(started)
+			return postgres.MigrationStep{
+				Name:       "v1",
+				SequenceNo: 1,
+				Sql:        `SELECT pg_sleep(2);`,
+			}, nil
+		})
+	}()
+	<-started
+
+	// Another run must give up waiting for the lock
+	startTime := time.Now()
+	err = db.RunMigrations(ctx, "migrations_lock", func(ctx context.Context, stepIdx int) (postgres.MigrationStep, error) {
+		return postgres.MigrationStep{}, nil
+	}, postgres.MigrationOptions{
+		MigrationLockTimeout: 500 * time.Millisecond,
+	})
+	if err == nil {
+		return errors.New("migration lock was acquired while held")
+	}
+	if !strings.Contains(err.Error(), "could not acquire migration lock") {
+		return fmt.Errorf("unexpected migration lock error [err=%v]", err.Error())
+	}
+	if elapsed := time.Since(startTime); elapsed > 1500*time.Millisecond {
+		return fmt.Errorf("migration lock wait took too long [elapsed=%v]", elapsed)
+	}
+
+	err = <-slowErrCh
+	if err != nil {
+		return fmt.Errorf("unable to run slow migration [err=%v]", err.Error())
+	}
+
+	// Once released, the lock can be acquired
+	err = db.RunMigrations(ctx, "migrations_lock", func(ctx context.Context, stepIdx int) (postgres.MigrationStep, error) {
+		return postgres.MigrationStep{}, nil
+	}, postgres.MigrationOptions{
+		MigrationLockTimeout: 500 * time.Millisecond,
+	})
+	if err != nil {
+		return fmt.Errorf("unable to run migrations after lock release [err=%v]", err.Error())
 	}
 
 	// Done
@@ -0,0 +1,327 @@
+// See the LICENSE file for license details.
+
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// -----------------------------------------------------------------------------
+
+// Mock is an in-memory test double that implements the ExecerQueryer interface. It records the
+// executed queries and returns the canned results set up with Expect.
+type Mock struct {
+	mutex        sync.Mutex
+	expectations []*MockExpectation
+	calls        []MockCall
+}
+
+// MockCall contains details about a query executed through the mock.
+type MockCall struct {
+	Sql  string
+	Args []interface{}
+}
+
+// MockExpectation defines the result to return when a query matches.
+type MockExpectation struct {
+	sql          string
+	args         []interface{}
+	rowsAffected int64
+	rows         [][]interface{}
+	err          error
+	used         bool
+}
+
+type mockRow struct {
+	values []interface{}
+}
+
+type mockRows struct {
+	ctx  context.Context
+	rows [][]interface{}
+	err  error
+}
+
+// -----------------------------------------------------------------------------
+
+// NewMock creates a new database mock.
+func NewMock() *Mock {
+	return &Mock{
+		mutex:        sync.Mutex{},
+		expectations: make([]*MockExpectation, 0),
+		calls:        make([]MockCall, 0),
+	}
+}
+
+// Expect registers a new expected query. Whitespace differences in the SQL sentence are ignored.
+// If no arguments are provided, the expectation matches regardless of the query arguments.
+//
+// Each expectation is used once and, if several of them match, they are used in the order they
+// were added. Copy operations are matched against "COPY table_name".
+func (m *Mock) Expect(sql string, args ...interface{}) *MockExpectation {
+	e := &MockExpectation{
+		sql:  normalizeMockSql(sql),
+		args: args,
+	}
+
+	m.mutex.Lock()
+	m.expectations = append(m.expectations, e)
+	m.mutex.Unlock()
+
+	// Done
+	return e
+}
+
+// Calls returns the list of queries executed through the mock.
+func (m *Mock) Calls() []MockCall {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return append([]MockCall{}, m.calls...)
+}
+
+// ExpectationsWereMet returns an error if any of the registered expectations was not used.
+func (m *Mock) ExpectationsWereMet() error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for _, e := range m.expectations {
+		if !e.used {
+			return fmt.Errorf("expectation not met [sql=%v]", e.sql)
+		}
+	}
+	return nil
+}
+
+// Exec executes an SQL statement against the mock.
+func (m *Mock) Exec(_ context.Context, sql string, args ...interface{}) (int64, error) {
+	e, err := m.match(sql, args)
+	if err != nil {
+		return 0, err
+	}
+	return e.rowsAffected, e.err
+}
+
+// QueryRow executes a SQL query against the mock.
+func (m *Mock) QueryRow(_ context.Context, sql string, args ...interface{}) Row {
+	e, err := m.match(sql, args)
+	if err == nil {
+		err = e.err
+	}
+	if err != nil {
+		return &mockRows{
+			err: err,
+		}
+	}
+	if len(e.rows) == 0 {
+		return &mockRows{
+			err: errNoRows,
+		}
+	}
+	return &mockRow{
+		values: e.rows[0],
+	}
+}
+
+// QueryRows executes a SQL query against the mock.
+func (m *Mock) QueryRows(ctx context.Context, sql string, args ...interface{}) Rows {
+	e, err := m.match(sql, args)
+	if err == nil {
+		err = e.err
+	}
+	if err != nil {
+		return &mockRows{
+			err: err,
+		}
+	}
+	return &mockRows{
+		ctx:  ctx,
+		rows: e.rows,
+	}
+}
+
+// Copy executes a SQL copy query against the mock. The copied rows are recorded as the call
+// arguments.
+func (m *Mock) Copy(ctx context.Context, tableName string, _ []string, cb CopyCallback) (int64, error) {
+	rows := make([]interface{}, 0)
+	for idx := 0; ; idx++ {
+		data, err := cb(ctx, idx)
+		if err != nil {
+			return 0, err
+		}
+		if data == nil {
+			break
+		}
+		rows = append(rows, data)
+	}
+
+	e, err := m.match("COPY "+tableName, rows)
+	if err != nil {
+		return 0, err
+	}
+	if e.err != nil {
+		return 0, e.err
+	}
+	return int64(len(rows)), nil
+}
+
+// ReturnResult sets the number of affected rows returned by Exec.
+func (e *MockExpectation) ReturnResult(rowsAffected int64) *MockExpectation {
+	e.rowsAffected = rowsAffected
+	return e
+}
+
+// ReturnRows sets the rows returned by QueryRow and QueryRows.
+func (e *MockExpectation) ReturnRows(rows ...[]interface{}) *MockExpectation {
+	e.rows = rows
+	return e
+}
+
+// ReturnError sets the error returned by the operation.
+func (e *MockExpectation) ReturnError(err error) *MockExpectation {
+	e.err = err
+	return e
+}
+
+func (m *Mock) match(sql string, args []interface{}) (*MockExpectation, error) {
+	normalizedSql := normalizeMockSql(sql)
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.calls = append(m.calls, MockCall{
+		Sql:  sql,
+		Args: args,
+	})
+
+	for _, e := range m.expectations {
+		if e.used || e.sql != normalizedSql {
+			continue
+		}
+		if len(e.args) > 0 && !reflect.DeepEqual(e.args, args) {
+			continue
+		}
+		e.used = true
+		return e, nil
+	}
+	return nil, fmt.Errorf("unexpected query [sql=%v]", normalizedSql)
+}
+
+func normalizeMockSql(sql string) string {
+	return strings.Join(strings.Fields(sql), " ")
+}
+
+// -----------------------------------------------------------------------------
+
+func (r *mockRow) Scan(dest ...interface{}) error {
+	if len(dest) != len(r.values) {
+		return fmt.Errorf("query returned %d columns but %d scan targets were provided", len(r.values), len(dest))
+	}
+	for idx, d := range dest {
+		err := assignMockValue(d, r.values[idx])
+		if err != nil {
+			return fmt.Errorf("unable to scan column #%d [err=%v]", idx+1, err.Error())
+		}
+	}
+
+	// Done
+	return nil
+}
+
+// ScanByName is not supported by the mock because canned rows do not have column names.
+func (r *mockRow) ScanByName(_ map[string]interface{}) error {
+	return errors.New("scan by name is not supported by mock rows")
+}
+
+func (r *mockRow) Values() ([]interface{}, error) {
+	values := make([]interface{}, len(r.values))
+	copy(values, r.values)
+	return values, nil
+}
+
+func (r *mockRows) Do(cb ScanRowsCallback) error {
+	if r.err != nil {
+		return r.err
+	}
+	for _, values := range r.rows {
+		cont, err := cb(r.ctx, &mockRow{
+			values: values,
+		})
+		if err != nil {
+			return err
+		}
+		if !cont {
+			break
+		}
+	}
+
+	// Done
+	return nil
+}
+
+func (r *mockRows) Scan(_ ...interface{}) error {
+	return r.err
+}
+
+func (r *mockRows) ScanByName(_ map[string]interface{}) error {
+	return r.err
+}
+
+func (r *mockRows) Values() ([]interface{}, error) {
+	return nil, r.err
+}
+
+func assignMockValue(dest interface{}, value interface{}) error {
+	if dest == nil {
+		return nil // Skip column
+	}
+	if scanner, ok := dest.(sql.Scanner); ok {
+		return scanner.Scan(value)
+	}
+
+	d := reflect.ValueOf(dest)
+	if d.Kind() != reflect.Pointer || d.IsNil() {
+		return errors.New("destination is not a pointer")
+	}
+	target := d.Elem()
+
+	if value == nil {
+		switch target.Kind() {
+		case reflect.Pointer, reflect.Slice, reflect.Map, reflect.Interface:
+			target.Set(reflect.Zero(target.Type()))
+			return nil
+		}
+		return errors.New("cannot scan NULL into a non-pointer destination")
+	}
+
+	v := reflect.ValueOf(value)
+	if target.Kind() == reflect.Pointer && !v.Type().AssignableTo(target.Type()) {
+		// Allocate the pointed value and assign to it
+		p := reflect.New(target.Type().Elem())
+		err := assignMockValue(p.Interface(), value)
+		if err != nil {
+			return err
+		}
+		target.Set(p)
+		return nil
+	}
+	if v.Type().AssignableTo(target.Type()) {
+		target.Set(v)
+		return nil
+	}
+	if v.Type().ConvertibleTo(target.Type()) && v.Kind() != reflect.String && target.Kind() != reflect.String {
+		target.Set(v.Convert(target.Type()))
+		return nil
+	}
+	return fmt.Errorf("cannot assign %v to %v", v.Type(), target.Type())
+}
+
+// -----------------------------------------------------------------------------
+
+var _ ExecerQueryer = &Mock{}
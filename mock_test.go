@@ -0,0 +1,99 @@
+// See the LICENSE file for license details.
+
+package postgres_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/mxmauro/go-postgres/v2"
+)
+
+// -----------------------------------------------------------------------------
+
+func TestMock(t *testing.T) {
+	var name string
+	var age *int
+
+	ctx := context.Background()
+	m := postgres.NewMock()
+
+	m.Expect(`SELECT name, age FROM users WHERE id = $1`, 1).ReturnRows(
+		[]interface{}{"john", int64(30)},
+	)
+	m.Expect(`SELECT name, age FROM users WHERE id = $1`, 2)
+	m.Expect(`UPDATE users SET name = $1`).ReturnResult(3)
+	m.Expect(`DELETE FROM users`).ReturnError(errors.New("forced failure"))
+
+	// Use it through the interface
+	var q postgres.ExecerQueryer = m
+
+	err := q.QueryRow(ctx, `SELECT name, age
+		FROM users WHERE id = $1`, 1).Scan(&name, &age)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if name != "john" || age == nil || *age != 30 {
+		t.Fatalf("unexpected values [name=%v/age=%v]", name, age)
+	}
+
+	err = q.QueryRow(ctx, `SELECT name, age FROM users WHERE id = $1`, 2).Scan(&name, &age)
+	if !postgres.IsNoRowsError(err) {
+		t.Fatalf("expected no rows error [err=%v]", err)
+	}
+
+	affected, err := q.Exec(ctx, `UPDATE users SET name = $1`, "jane")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if affected != 3 {
+		t.Fatalf("affected rows mismatch [got=%d/expected=3]", affected)
+	}
+
+	_, err = q.Exec(ctx, `DELETE FROM users`)
+	if err == nil || err.Error() != "forced failure" {
+		t.Fatalf("expected forced failure [err=%v]", err)
+	}
+
+	_, err = q.Exec(ctx, `DROP TABLE users`)
+	if err == nil {
+		t.Fatal("unexpected query succeeded")
+	}
+
+	err = m.ExpectationsWereMet()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(m.Calls()) != 5 {
+		t.Fatalf("calls count mismatch [got=%d/expected=5]", len(m.Calls()))
+	}
+}
+
+func TestMockRows(t *testing.T) {
+	ctx := context.Background()
+	m := postgres.NewMock()
+
+	m.Expect(`SELECT id FROM users`).ReturnRows(
+		[]interface{}{1},
+		[]interface{}{2},
+		[]interface{}{3},
+	)
+
+	sum := 0
+	err := m.QueryRows(ctx, `SELECT id FROM users`).Do(func(ctx context.Context, row postgres.Row) (bool, error) {
+		var id int
+
+		err := row.Scan(&id)
+		if err == nil {
+			sum += id
+		}
+		return true, err
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if sum != 6 {
+		t.Fatalf("sum mismatch [got=%d/expected=6]", sum)
+	}
+}
@@ -0,0 +1,258 @@
+// See the LICENSE file for license details.
+
+package postgres_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/mxmauro/go-postgres/v2"
+)
+
+// -----------------------------------------------------------------------------
+
+func TestExtendedSettings(t *testing.T) {
+	ctx := context.Background()
+
+	newOpts := func(settings map[string]string) postgres.Options {
+		return postgres.Options{
+			Host:             "127.0.0.1",
+			Port:             5432,
+			User:             "postgres",
+			Name:             "postgres",
+			ExtendedSettings: settings,
+		}
+	}
+
+	// Keys must not be able to inject additional keywords
+	for _, key := range []string{"sslmode=require host", "application_name ", "Application_Name", "search-path", ""} {
+		_, err := postgres.New(ctx, newOpts(map[string]string{
+			key: "127.0.0.2",
+		}))
+		if err == nil {
+			t.Fatalf("invalid extended setting name was accepted [name=%v]", key)
+		}
+	}
+
+	// Values are quoted so spaces, quotes and backslashes are kept within them
+	db, err := postgres.New(ctx, newOpts(map[string]string{
+		"application_name": `my app' host='127.0.0.2' \`,
+	}))
+	if err != nil {
+		t.Fatalf("valid extended setting was rejected [err=%v]", err.Error())
+	}
+	db.Close()
+}
+
+func TestPrePingWithAcquireWait(t *testing.T) {
+	var pid int
+	var waitCount atomic.Int32
+	var beforeAcquireCount atomic.Int32
+
+	ctx := context.Background()
+
+	db := openTestDatabaseWithOptions(ctx, t, func(opts *postgres.Options) {
+		opts.MaxConns = 1
+		opts.PrePing = true
+		opts.BeforeAcquire = func(_ context.Context, _ *pgx.Conn) bool {
+			beforeAcquireCount.Add(1)
+			return true
+		}
+		opts.OnAcquireWait = func(_ time.Duration) {
+			waitCount.Add(1)
+		}
+	})
+	defer db.Close()
+
+	err := db.WithinConn(ctx, func(ctx context.Context, conn *postgres.Conn) error {
+		return conn.QueryRow(ctx, `SELECT pg_backend_pid()`).Scan(&pid)
+	})
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+
+	// Kill the pooled connection from another driver
+	adminDb := openTestDatabase(ctx, t)
+	defer adminDb.Close()
+
+	_, err = adminDb.Exec(ctx, `SELECT pg_terminate_backend($1)`, pid)
+	if err == nil {
+		_, err = adminDb.Exec(ctx, `SELECT pg_sleep(0.1)`)
+	}
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+
+	// The dead connection must be discarded by the ping even if the acquire wait hook is set
+	err = db.WithinConn(ctx, func(ctx context.Context, conn *postgres.Conn) error {
+		_, err := conn.Exec(ctx, `SELECT 1`)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("dead connection was handed out [err=%v]", err.Error())
+	}
+	if waitCount.Load() == 0 || beforeAcquireCount.Load() == 0 {
+		t.Fatalf("acquire hooks were not called [wait=%v/before=%v]", waitCount.Load(), beforeAcquireCount.Load())
+	}
+}
+
+func TestDefaultSchema(t *testing.T) {
+	ctx := context.Background()
+
+	db := openTestDatabaseWithOptions(ctx, t, func(opts *postgres.Options) {
+		opts.DefaultSchema = "go_postgres_test_schema"
+	})
+	defer db.Close()
+
+	_, err := db.Exec(ctx, `DROP SCHEMA IF EXISTS go_postgres_test_schema CASCADE`)
+	if err == nil {
+		_, err = db.Exec(ctx, `CREATE SCHEMA go_postgres_test_schema`)
+	}
+	if err == nil {
+		_, err = db.Exec(ctx, `CREATE TABLE go_postgres_test_schema.items (id INT NOT NULL PRIMARY KEY)`)
+	}
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+	defer func() {
+		_, _ = db.Exec(ctx, `DROP SCHEMA IF EXISTS go_postgres_test_schema CASCADE`)
+	}()
+
+	// Copy into the unqualified name, which uses the default schema, and into the qualified one
+	for _, tableName := range []string{"items", "go_postgres_test_schema.items"} {
+		_, err = db.Copy(ctx, tableName, []string{"id"}, func(ctx context.Context, idx int) ([]interface{}, error) {
+			if idx >= 2 {
+				return nil, nil
+			}
+			if tableName == "items" {
+				return []interface{}{idx + 1}, nil
+			}
+			return []interface{}{idx + 3}, nil
+		})
+		if err != nil {
+			t.Fatalf("unable to copy rows [table=%v/err=%v]", tableName, err.Error())
+		}
+	}
+
+	for _, tableName := range []string{"items", "go_postgres_test_schema.items"} {
+		result, err := postgres.GetByKeysOrdered(ctx, db, tableName, "id", []int{4, 1, 5},
+			func(row postgres.Row) (int, int, error) {
+				var id int
+
+				err := row.Scan(&id)
+				return id, id, err
+			},
+		)
+		if err != nil {
+			t.Fatalf("unable to get rows [table=%v/err=%v]", tableName, err.Error())
+		}
+		if result[0] == nil || *result[0] != 4 || result[1] == nil || *result[1] != 1 || result[2] != nil {
+			t.Fatalf("unexpected rows [table=%v]", tableName)
+		}
+	}
+}
+
+func TestAcquireReleaseHooks(t *testing.T) {
+	var acquires atomic.Int32
+	var releases atomic.Int32
+
+	ctx := context.Background()
+
+	db := openTestDatabaseWithOptions(ctx, t, func(opts *postgres.Options) {
+		// Reject the first connection and destroy every released one
+		opts.BeforeAcquire = func(_ context.Context, _ *pgx.Conn) bool {
+			return acquires.Add(1) > 1
+		}
+		opts.AfterRelease = func(_ *pgx.Conn) bool {
+			releases.Add(1)
+			return false
+		}
+	})
+	defer db.Close()
+
+	_, err := db.Exec(ctx, `SELECT 1`)
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+	if acquires.Load() < 2 {
+		t.Fatalf("rejected connection was not replaced [acquires=%v]", acquires.Load())
+	}
+
+	// The hook runs in the background after releasing the connection
+	deadline := time.Now().Add(5 * time.Second)
+	for releases.Load() == 0 || db.PoolStats().ClosedConnsCount < 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("connections were not destroyed [releases=%v/closed=%v]", releases.Load(),
+				db.PoolStats().ClosedConnsCount)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestVerifyConnect(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	opts := postgres.Options{
+		Host: "127.0.0.1",
+		Port: 1,
+		User: "postgres",
+		Name: "postgres",
+	}
+
+	// Pools are lazy by default
+	db, err := postgres.New(ctx, opts)
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+	db.Close()
+
+	// An unreachable server must be reported by New
+	opts.VerifyConnect = true
+	start := time.Now()
+	_, err = postgres.New(ctx, opts)
+	if err == nil {
+		t.Fatal("unreachable server was accepted")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("connection verification did not fail fast [elapsed=%v]", elapsed)
+	}
+}
+
+func TestConnectRetries(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	opts := postgres.Options{
+		Host:                "127.0.0.1",
+		Port:                1,
+		User:                "postgres",
+		Name:                "postgres",
+		ConnectRetries:      2,
+		ConnectRetryBackoff: "20ms",
+	}
+
+	// Retries wait 20ms and 40ms before giving up
+	start := time.Now()
+	_, err := postgres.New(ctx, opts)
+	if err == nil {
+		t.Fatal("unreachable server was accepted")
+	}
+	elapsed := time.Since(start)
+	if elapsed < 60*time.Millisecond {
+		t.Fatalf("connection was not retried [elapsed=%v]", elapsed)
+	}
+	if elapsed > 5*time.Second {
+		t.Fatalf("connection retries did not fail fast [elapsed=%v]", elapsed)
+	}
+
+	// An invalid backoff is rejected
+	opts.ConnectRetryBackoff = "soon"
+	_, err = postgres.New(ctx, opts)
+	if err == nil {
+		t.Fatal("invalid retry backoff was accepted")
+	}
+}
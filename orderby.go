@@ -0,0 +1,88 @@
+// See the LICENSE file for license details.
+
+package postgres
+
+import (
+	"fmt"
+	"strings"
+)
+
+// -----------------------------------------------------------------------------
+
+// SortSpec specifies a field to sort by and the direction.
+type SortSpec struct {
+	Field string
+	Desc  bool
+}
+
+// OrderByBuilder builds ORDER BY clauses from sort specifications received from untrusted sources,
+// like the query string of list endpoints. Only the fields present in the allowlist are accepted.
+type OrderByBuilder struct {
+	columns map[string]string
+}
+
+// -----------------------------------------------------------------------------
+
+// NewOrderByBuilder creates a new ORDER BY builder. The allowlist maps the field names exposed to
+// clients to the real column names. Column names can be qualified with a table name or alias, for
+// e.g. "u.created_at".
+func NewOrderByBuilder(allowlist map[string]string) *OrderByBuilder {
+	columns := make(map[string]string, len(allowlist))
+	for field, column := range allowlist {
+		columns[field] = column
+	}
+	return &OrderByBuilder{
+		columns: columns,
+	}
+}
+
+// ParseSortSpecs parses a comma separated list of fields, each one optionally prefixed with a minus
+// sign to sort in descending order, for e.g. "name,-created_at". Empty items are ignored.
+func ParseSortSpecs(s string) []SortSpec {
+	specs := make([]SortSpec, 0)
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		desc := false
+		if strings.HasPrefix(item, "-") {
+			desc = true
+			item = strings.TrimSpace(item[1:])
+		} else if strings.HasPrefix(item, "+") {
+			item = strings.TrimSpace(item[1:])
+		}
+		if len(item) > 0 {
+			specs = append(specs, SortSpec{
+				Field: item,
+				Desc:  desc,
+			})
+		}
+	}
+	return specs
+}
+
+// Build returns an ORDER BY clause, including a leading space, with the columns associated to the
+// requested fields properly quoted. An empty string is returned if no specification is provided
+// and an error if a field is not present in the allowlist.
+func (b *OrderByBuilder) Build(specs ...SortSpec) (string, error) {
+	if len(specs) == 0 {
+		return "", nil
+	}
+
+	sb := strings.Builder{}
+	_, _ = sb.WriteString(" ORDER BY ")
+	for idx, spec := range specs {
+		column, ok := b.columns[spec.Field]
+		if !ok {
+			return "", fmt.Errorf("unable to sort by unknown field \"%s\"", spec.Field)
+		}
+		if idx > 0 {
+			_, _ = sb.WriteString(", ")
+		}
+		_, _ = sb.WriteString(quoteParameterName(column))
+		if spec.Desc {
+			_, _ = sb.WriteString(" DESC")
+		} else {
+			_, _ = sb.WriteString(" ASC")
+		}
+	}
+	return sb.String(), nil
+}
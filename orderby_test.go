@@ -0,0 +1,36 @@
+// See the LICENSE file for license details.
+
+package postgres_test
+
+import (
+	"testing"
+
+	"github.com/mxmauro/go-postgres/v2"
+)
+
+// -----------------------------------------------------------------------------
+
+func TestOrderByBuilder(t *testing.T) {
+	b := postgres.NewOrderByBuilder(map[string]string{
+		"name":      "u.full_name",
+		"createdAt": "created_at",
+	})
+
+	clause, err := b.Build(postgres.ParseSortSpecs("name, -createdAt,,")...)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if clause != ` ORDER BY "u"."full_name" ASC, "created_at" DESC` {
+		t.Fatalf("order by clause mismatch [got=%v]", clause)
+	}
+
+	clause, err = b.Build()
+	if err != nil || len(clause) != 0 {
+		t.Fatalf("unexpected order by clause for empty specs [got=%v/err=%v]", clause, err)
+	}
+
+	_, err = b.Build(postgres.ParseSortSpecs(`name,"; DROP TABLE users; --`)...)
+	if err == nil {
+		t.Fatal("unknown field was accepted")
+	}
+}
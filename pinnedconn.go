@@ -0,0 +1,62 @@
+// See the LICENSE file for license details.
+
+package postgres
+
+import (
+	"context"
+	"sync"
+)
+
+// -----------------------------------------------------------------------------
+
+type pinnedConnCtxKey struct{}
+
+type pinnedConnValue struct {
+	db   *Database
+	conn *Conn
+}
+
+// -----------------------------------------------------------------------------
+
+// WithPinnedConn acquires a connection from the pool and binds it to the returned context. The
+// Exec, ExecTag, QueryRow, QueryRows, Copy, WithinTx and WithinConn methods of the database called
+// with that context are executed on the pinned connection instead of a pooled one.
+//
+// The returned release function must be called to return the connection to the pool. The context
+// must not be used after releasing the connection.
+func WithPinnedConn(ctx context.Context, db *Database) (context.Context, func(), error) {
+	ctx = db.withAcquireTracking(ctx)
+	conn, err := db.acquireConn(ctx)
+	if err != nil {
+		return ctx, func() {}, db.handleError(ctx, db.newAcquireError(ctx, err, "unable to acquire a connection from the pool"))
+	}
+
+	once := sync.Once{}
+	release := func() {
+		once.Do(conn.Release)
+	}
+
+	ctx = context.WithValue(ctx, pinnedConnCtxKey{}, &pinnedConnValue{
+		db: db,
+		conn: &Conn{
+			db:   db,
+			conn: conn,
+		},
+	})
+
+	// Done
+	return ctx, release, nil
+}
+
+// -----------------------------------------------------------------------------
+
+func (db *Database) getPinnedConn(ctx context.Context) *Conn {
+	if ctx == nil {
+		return nil
+	}
+	pc, ok := ctx.Value(pinnedConnCtxKey{}).(*pinnedConnValue)
+	if !ok || pc.db != db {
+		return nil
+	}
+	return pc.conn
+}
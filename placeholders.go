@@ -0,0 +1,177 @@
+// See the LICENSE file for license details.
+
+package postgres
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// -----------------------------------------------------------------------------
+
+// checkSqlPlaceholders verifies the highest $N placeholder referenced by the SQL sentence matches the
+// number of provided arguments.
+func checkSqlPlaceholders(sql string, args []interface{}) error {
+	argsCount := 0
+	for idx, arg := range args {
+		switch arg.(type) {
+		case pgx.QueryRewriter:
+			if idx == 0 {
+				return nil // Named arguments are rewritten by the underlying library
+			}
+		case pgx.QueryExecMode, pgx.QueryResultFormats, pgx.QueryResultFormatsByOID:
+			continue // Query options are not arguments
+		}
+		argsCount += 1
+	}
+
+	maxPlaceholder, err := getMaxSqlPlaceholder(sql)
+	if err != nil {
+		return err
+	}
+	if maxPlaceholder != argsCount {
+		return fmt.Errorf("statement references %d placeholders but %d arguments were provided", maxPlaceholder,
+			argsCount)
+	}
+
+	// Done
+	return nil
+}
+
+// getMaxSqlPlaceholder returns the highest $N placeholder found in the SQL sentence skipping
+// comments, strings, quoted identifiers and dollar-quoted strings.
+func getMaxSqlPlaceholder(sql string) (int, error) {
+	maxPlaceholder := 0
+
+	err := scanSqlPlaceholders(sql, func(_ int, _ int, n int) {
+		if n > maxPlaceholder {
+			maxPlaceholder = n
+		}
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	// Done
+	return maxPlaceholder, nil
+}
+
+// renumberSqlPlaceholders returns the SQL sentence with the number of each $N placeholder increased
+// by delta.
+func renumberSqlPlaceholders(sql string, delta int) (string, error) {
+	sb := strings.Builder{}
+	lastOfs := 0
+
+	err := scanSqlPlaceholders(sql, func(startOfs int, endOfs int, n int) {
+		_, _ = sb.WriteString(sql[lastOfs:startOfs])
+		_, _ = sb.WriteString("$" + strconv.Itoa(n+delta))
+		lastOfs = endOfs
+	})
+	if err != nil {
+		return "", err
+	}
+	_, _ = sb.WriteString(sql[lastOfs:])
+
+	// Done
+	return sb.String(), nil
+}
+
+// scanSqlPlaceholders calls cb with the location and number of each $N placeholder found in the SQL
+// sentence skipping comments, strings, quoted identifiers and dollar-quoted strings.
+func scanSqlPlaceholders(sql string, cb func(startOfs int, endOfs int, n int)) error {
+	sqlLen := len(sql)
+	for ofs := 0; ofs < sqlLen; {
+		deltaOfs, err := skipSqlComment(sql[ofs:])
+		if err != nil {
+			return err
+		}
+		if deltaOfs > 0 {
+			ofs += deltaOfs
+			continue
+		}
+
+		ch := sql[ofs]
+		switch {
+		case (ch == 'E' || ch == 'e') && ofs+1 < sqlLen && sql[ofs+1] == '\'' && !endsWithIdentifierChar(sql[:ofs]):
+			// Escape string
+			ofs += 2
+			for {
+				if ofs >= sqlLen {
+					return errors.New("invalid SQL content (open string)")
+				}
+				if sql[ofs] == '\\' {
+					ofs += 2
+					continue
+				}
+				ofs += 1
+				if sql[ofs-1] == '\'' {
+					if ofs >= sqlLen || sql[ofs] != '\'' {
+						break // End of string
+					}
+					// Double single-quotes
+					ofs += 1
+				}
+			}
+
+		case ch == '\'' || ch == '"':
+			// Single-quote string or quoted identifier
+			ofs += 1
+			for {
+				if ofs >= sqlLen {
+					return errors.New("invalid SQL content (open string)")
+				}
+				ofs += 1
+				if sql[ofs-1] == ch {
+					if ofs >= sqlLen || sql[ofs] != ch {
+						break // End of string
+					}
+					// Doubled quotes
+					ofs += 1
+				}
+			}
+
+		case ch == '$' && !endsWithIdentifierChar(sql[:ofs]):
+			startOfs := ofs
+			ofs += 1
+
+			if ofs < sqlLen && sql[ofs] >= '0' && sql[ofs] <= '9' {
+				// Placeholder
+				n := 0
+				for ofs < sqlLen && sql[ofs] >= '0' && sql[ofs] <= '9' {
+					n = n*10 + int(sql[ofs]-'0')
+					ofs += 1
+				}
+				cb(startOfs, ofs, n)
+				continue
+			}
+
+			// Dollar tag
+			for ofs < sqlLen && sql[ofs] != '$' && (sql[ofs] == '_' || (sql[ofs] >= '0' && sql[ofs] <= '9') ||
+				(sql[ofs] >= 'A' && sql[ofs] <= 'Z') || (sql[ofs] >= 'a' && sql[ofs] <= 'z')) {
+				ofs += 1
+			}
+			if ofs >= sqlLen || sql[ofs] != '$' {
+				continue // Not a dollar tag
+			}
+			ofs += 1
+			tag := sql[startOfs:ofs]
+
+			// Find the next tag
+			deltaOfs = strings.Index(sql[ofs:], tag)
+			if deltaOfs < 0 {
+				return errors.New("invalid SQL content (open dollar tag)")
+			}
+			ofs += deltaOfs + len(tag)
+
+		default:
+			ofs += 1
+		}
+	}
+
+	// Done
+	return nil
+}
@@ -0,0 +1,123 @@
+// See the LICENSE file for license details.
+
+package postgres_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/mxmauro/go-postgres/v2"
+)
+
+// -----------------------------------------------------------------------------
+
+func TestPlaceholdersCheck(t *testing.T) {
+	// NOTE: Connections are not established until needed so no server is required. The context is
+	//       cancelled so statements passing the check fail without reaching the server.
+	db, err := postgres.New(context.Background(), postgres.Options{
+		Host:              "127.0.0.1",
+		Port:              5432,
+		User:              "postgres",
+		Name:              "postgres",
+		DebugPlaceholders: true,
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name  string
+		sql   string
+		args  []interface{}
+		valid bool
+	}{
+		{"simple", `SELECT $1, $2`, []interface{}{1, 2}, true},
+		{"repeated", `SELECT $1 WHERE $1 > $2`, []interface{}{1, 2}, true},
+		{"string", `SELECT '$2', $1`, []interface{}{1}, true},
+		{"doubled quotes", `SELECT 'it''s $2', $1`, []interface{}{1}, true},
+		{"escape string", `SELECT E'it\'s $2', $1`, []interface{}{1}, true},
+		{"quoted identifier", `SELECT "$2" FROM t WHERE id = $1`, []interface{}{1}, true},
+		{"dollar tag", `SELECT $tag$ $5 $tag$, $1`, []interface{}{1}, true},
+		{"empty dollar tag", `SELECT $$ $5 $$, $1`, []interface{}{1}, true},
+		{"line comment", "SELECT $1 -- $2\n", []interface{}{1}, true},
+		{"block comment", `SELECT $1 /* $2 */`, []interface{}{1}, true},
+		{"exec mode argument", `SELECT $1`, []interface{}{pgx.QueryExecModeSimpleProtocol, 1}, true},
+		{"result formats argument", `SELECT $1, $2`, []interface{}{pgx.QueryResultFormats{pgx.TextFormatCode}, 1, 2}, true},
+		{"missing argument", `SELECT $1, $2`, []interface{}{1}, false},
+		{"extra argument", `SELECT $1`, []interface{}{1, 2}, false},
+		{"placeholder after string", `SELECT '$1', $2`, []interface{}{1}, false},
+		{"open string", `SELECT '$1`, []interface{}{1}, false},
+		{"open dollar tag", `SELECT $tag$ $1`, []interface{}{1}, false},
+	}
+	for _, test := range tests {
+		_, err = db.Exec(ctx, test.sql, test.args...)
+		if err == nil {
+			t.Fatalf("unexpected success [test=%v]", test.name)
+		}
+		if errors.Is(err, context.Canceled) != test.valid {
+			t.Fatalf("unexpected placeholders check result [test=%v/err=%v]", test.name, err.Error())
+		}
+		if !test.valid {
+			var pgErr *postgres.Error
+
+			if !errors.As(err, &pgErr) {
+				t.Fatalf("unexpected error type [test=%v/err=%T]", test.name, err)
+			}
+			if !strings.HasPrefix(err.Error(), "statement validation failed [sql=") {
+				t.Fatalf("unexpected error message [test=%v/err=%v]", test.name, err.Error())
+			}
+		}
+	}
+}
+
+func TestMaxErrorSqlLength(t *testing.T) {
+	db, err := postgres.New(context.Background(), postgres.Options{
+		Host:              "127.0.0.1",
+		Port:              5432,
+		User:              "postgres",
+		Name:              "postgres",
+		DebugPlaceholders: true,
+		MaxErrorSqlLength: 20,
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer db.Close()
+
+	tests := []struct {
+		name        string
+		sql         string
+		expectedSql string
+	}{
+		{"short", `SELECT $1, $2`, `SELECT $1, $2`},
+		{"long", `SELECT $1, $2 FROM some_table WHERE id = 1`, `SELECT $1, $2 FRO...`},
+		{"multi-byte", `SELECT $1, $2, "ñandúñandú"`, `SELECT $1, $2, "...`},
+	}
+	for _, test := range tests {
+		_, err = db.Exec(context.Background(), test.sql, 1)
+		if err == nil {
+			t.Fatalf("unexpected success [test=%v]", test.name)
+		}
+		msg := err.Error()
+		startIdx := strings.Index(msg, "[sql=")
+		endIdx := strings.Index(msg, "] [err=")
+		if startIdx < 0 || endIdx < startIdx {
+			t.Fatalf("unexpected error message [test=%v/err=%v]", test.name, msg)
+		}
+		sql := msg[startIdx+5 : endIdx]
+		if sql != test.expectedSql {
+			t.Fatalf("sql mismatch [test=%v/got=%v]", test.name, sql)
+		}
+		if len(sql) > 20 || !utf8.ValidString(sql) {
+			t.Fatalf("invalid truncated sql [test=%v/got=%v]", test.name, sql)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+// See the LICENSE file for license details.
+
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mxmauro/go-postgres/v2"
+)
+
+// -----------------------------------------------------------------------------
+
+func TestPoolSaturation(t *testing.T) {
+	ctx := context.Background()
+
+	db := openTestDatabaseWithOptions(ctx, t, func(opts *postgres.Options) {
+		opts.MaxConns = 1
+	})
+	defer db.Close()
+
+	// Hold the only connection
+	_, release, err := postgres.WithPinnedConn(ctx, db)
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+	defer release()
+
+	checkSaturated := func(name string, err error) {
+		if postgres.TypeOfError(err) != postgres.ErrorTypePoolSaturated {
+			t.Fatalf("unexpected %v error [err=%v]", name, err)
+		}
+	}
+
+	timeoutCtx := func() (context.Context, context.CancelFunc) {
+		return context.WithTimeout(ctx, 200*time.Millisecond)
+	}
+
+	c, cancel := timeoutCtx()
+	_, err = db.Exec(c, `SELECT 1`)
+	cancel()
+	checkSaturated("Exec", err)
+
+	c, cancel = timeoutCtx()
+	_, err = db.ExecTag(c, `SELECT 1`)
+	cancel()
+	checkSaturated("ExecTag", err)
+
+	c, cancel = timeoutCtx()
+	err = db.QueryRow(c, `SELECT 1`).Scan(new(int))
+	cancel()
+	checkSaturated("QueryRow", err)
+
+	c, cancel = timeoutCtx()
+	err = db.QueryRows(c, `SELECT 1`).Do(func(ctx context.Context, row postgres.Row) (bool, error) {
+		return true, nil
+	})
+	cancel()
+	checkSaturated("QueryRows", err)
+
+	c, cancel = timeoutCtx()
+	_, err = db.QueryChan(c, `SELECT 1`)
+	cancel()
+	checkSaturated("QueryChan", err)
+}
+
+func TestQueryTimeoutIsNotPoolSaturation(t *testing.T) {
+	ctx := context.Background()
+
+	db := openTestDatabaseWithOptions(ctx, t, func(opts *postgres.Options) {
+		opts.MaxConns = 1
+	})
+	defer db.Close()
+
+	checkNotSaturated := func(name string, err error) {
+		if err == nil {
+			t.Fatalf("unexpected %v success", name)
+		}
+		if postgres.TypeOfError(err) == postgres.ErrorTypePoolSaturated {
+			t.Fatalf("query timeout reported as pool saturation [method=%v/err=%v]", name, err)
+		}
+	}
+
+	// The connection is obtained but the query itself exceeds the deadline
+	c, cancel := context.WithTimeout(ctx, 200*time.Millisecond)
+	_, err := db.Exec(c, `SELECT pg_sleep(5)`)
+	cancel()
+	checkNotSaturated("Exec", err)
+
+	c, cancel = context.WithTimeout(ctx, 200*time.Millisecond)
+	err = db.QueryRow(c, `SELECT pg_sleep(5)`).Scan(new(interface{}))
+	cancel()
+	checkNotSaturated("QueryRow", err)
+}
@@ -0,0 +1,248 @@
+// See the LICENSE file for license details.
+
+package postgres
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// -----------------------------------------------------------------------------
+
+const (
+	minPoolManagerEvictionInterval = time.Second
+)
+
+// -----------------------------------------------------------------------------
+
+// PoolManagerOptions defines the options used to create a PoolManager.
+type PoolManagerOptions struct {
+	// Resolve returns the options used to create the database driver associated to the given key,
+	// for e.g. the connection settings of a tenant database. Mandatory.
+	Resolve func(ctx context.Context, key string) (Options, error)
+
+	// IdleTimeout closes the database drivers that were not requested for the specified duration.
+	// Zero means they are never closed because of inactivity.
+	IdleTimeout time.Duration
+
+	// MaxPools sets the maximum number of database drivers kept open. When the limit is reached, the
+	// least recently requested one is closed before creating a new one. Zero means no limit.
+	MaxPools int
+}
+
+// PoolManager lazily creates and caches a database driver, each one with its own connection pool,
+// per key. Useful to implement multi-tenant applications where each tenant has its own database.
+//
+// Drivers returned by Get are reference counted and evicted ones are not closed until all their
+// references are released, so do not keep them beyond the operation being executed. Always call Get
+// again to retrieve them.
+type PoolManager struct {
+	mutex     sync.Mutex
+	opts      PoolManagerOptions
+	pools     map[string]*managedPool
+	closed    bool
+	stopCh    chan struct{}
+	evictorWg sync.WaitGroup
+}
+
+type managedPool struct {
+	db       *Database
+	lastUsed time.Time
+	refs     int
+	evicted  bool
+}
+
+// -----------------------------------------------------------------------------
+
+// NewPoolManager creates a new pool manager.
+func NewPoolManager(opts PoolManagerOptions) (*PoolManager, error) {
+	if opts.Resolve == nil {
+		return nil, errors.New("invalid resolve callback")
+	}
+	if opts.IdleTimeout < 0 {
+		return nil, errors.New("invalid idle timeout value")
+	}
+	if opts.MaxPools < 0 {
+		return nil, errors.New("invalid max pools value")
+	}
+
+	pm := &PoolManager{
+		mutex:  sync.Mutex{},
+		opts:   opts,
+		pools:  make(map[string]*managedPool),
+		stopCh: make(chan struct{}),
+	}
+
+	if opts.IdleTimeout > 0 {
+		interval := opts.IdleTimeout / 2
+		if interval < minPoolManagerEvictionInterval {
+			interval = minPoolManagerEvictionInterval
+		}
+
+		pm.evictorWg.Add(1)
+		go func() {
+			defer pm.evictorWg.Done()
+
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-pm.stopCh:
+					return
+				case <-ticker.C:
+					pm.evictIdle()
+				}
+			}
+		}()
+	}
+
+	// Done
+	return pm, nil
+}
+
+// Get returns the database driver associated to the given key, creating it if needed, along with
+// a function that must be called to release it once the operation being executed ends.
+func (pm *PoolManager) Get(ctx context.Context, key string) (*Database, func(), error) {
+	pm.mutex.Lock()
+	if pm.closed {
+		pm.mutex.Unlock()
+		return nil, nil, errors.New("pool manager is closed")
+	}
+	mp, ok := pm.pools[key]
+	if ok {
+		release := pm.acquire(mp)
+		pm.mutex.Unlock()
+		return mp.db, release, nil
+	}
+	pm.mutex.Unlock()
+
+	// Create the database driver without holding the lock because it can take some time
+	opts, err := pm.opts.Resolve(ctx, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	db, err := New(ctx, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pm.mutex.Lock()
+	defer pm.mutex.Unlock()
+
+	if pm.closed {
+		go db.Close()
+		return nil, nil, errors.New("pool manager is closed")
+	}
+
+	// Another goroutine may have created it in the meantime
+	mp, ok = pm.pools[key]
+	if ok {
+		go db.Close()
+		return mp.db, pm.acquire(mp), nil
+	}
+
+	// Make room for the new one if the limit was reached
+	if pm.opts.MaxPools > 0 && len(pm.pools) >= pm.opts.MaxPools {
+		pm.evictLeastRecentlyUsed()
+	}
+
+	mp = &managedPool{
+		db: db,
+	}
+	pm.pools[key] = mp
+
+	// Done
+	return db, pm.acquire(mp), nil
+}
+
+// Len returns the number of open database drivers.
+func (pm *PoolManager) Len() int {
+	pm.mutex.Lock()
+	defer pm.mutex.Unlock()
+
+	return len(pm.pools)
+}
+
+// Close closes all the database drivers and stops the manager. Drivers still in use are closed
+// when their last reference is released.
+func (pm *PoolManager) Close() {
+	pm.mutex.Lock()
+	if pm.closed {
+		pm.mutex.Unlock()
+		return
+	}
+	pm.closed = true
+	pools := pm.pools
+	pm.pools = make(map[string]*managedPool)
+	close(pm.stopCh)
+	pm.mutex.Unlock()
+
+	pm.evictorWg.Wait()
+
+	pm.mutex.Lock()
+	defer pm.mutex.Unlock()
+
+	for _, mp := range pools {
+		pm.evict(mp)
+	}
+}
+
+// NOTE: The caller must hold the lock.
+func (pm *PoolManager) acquire(mp *managedPool) func() {
+	once := sync.Once{}
+
+	mp.refs += 1
+	mp.lastUsed = time.Now()
+	return func() {
+		once.Do(func() {
+			pm.mutex.Lock()
+			defer pm.mutex.Unlock()
+
+			mp.refs -= 1
+			mp.lastUsed = time.Now()
+			if mp.evicted && mp.refs == 0 {
+				go mp.db.Close()
+			}
+		})
+	}
+}
+
+// NOTE: The caller must hold the lock.
+func (pm *PoolManager) evict(mp *managedPool) {
+	mp.evicted = true
+	if mp.refs == 0 {
+		go mp.db.Close()
+	}
+}
+
+func (pm *PoolManager) evictIdle() {
+	pm.mutex.Lock()
+	defer pm.mutex.Unlock()
+
+	now := time.Now()
+	for key, mp := range pm.pools {
+		if mp.refs == 0 && now.Sub(mp.lastUsed) >= pm.opts.IdleTimeout {
+			delete(pm.pools, key)
+			pm.evict(mp)
+		}
+	}
+}
+
+// NOTE: The caller must hold the lock.
+func (pm *PoolManager) evictLeastRecentlyUsed() {
+	var lru *managedPool
+
+	lruKey := ""
+	for key, mp := range pm.pools {
+		if lru == nil || mp.lastUsed.Before(lru.lastUsed) {
+			lruKey = key
+			lru = mp
+		}
+	}
+	if lru != nil {
+		delete(pm.pools, lruKey)
+		pm.evict(lru)
+	}
+}
@@ -0,0 +1,89 @@
+// See the LICENSE file for license details.
+
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mxmauro/go-postgres/v2"
+)
+
+// -----------------------------------------------------------------------------
+
+func TestPoolManager(t *testing.T) {
+	ctx := context.Background()
+
+	// NOTE: Connections are not established until needed so no server is required.
+	pm, err := postgres.NewPoolManager(postgres.PoolManagerOptions{
+		Resolve: func(_ context.Context, key string) (postgres.Options, error) {
+			return postgres.Options{
+				Host: "127.0.0.1",
+				Port: 5432,
+				User: "postgres",
+				Name: key,
+			}, nil
+		},
+		IdleTimeout: time.Second,
+		MaxPools:    2,
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer pm.Close()
+
+	db1, release1, err := pm.Get(ctx, "tenant1")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	db1Again, release1Again, err := pm.Get(ctx, "tenant1")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	release1Again()
+	if db1 != db1Again {
+		t.Fatal("database driver was not cached")
+	}
+
+	for _, key := range []string{"tenant2", "tenant3"} {
+		_, release, err := pm.Get(ctx, key)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		release()
+	}
+	if n := pm.Len(); n != 2 {
+		t.Fatalf("pool count mismatch [got=%d/expected=2]", n)
+	}
+
+	// The evicted driver must remain open until released
+	time.Sleep(100 * time.Millisecond)
+	if db1.PoolStats().MaxConns == 0 {
+		t.Fatal("database driver in use was closed")
+	}
+	release1()
+	release1()
+	deadline := time.Now().Add(5 * time.Second)
+	for db1.PoolStats().MaxConns != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("released database driver was not closed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Wait for the idle eviction
+	deadline = time.Now().Add(5 * time.Second)
+	for pm.Len() > 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("idle pools were not evicted")
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	pm.Close()
+	_, _, err = pm.Get(ctx, "tenant1")
+	if err == nil {
+		t.Fatal("closed pool manager returned a database driver")
+	}
+}
@@ -11,25 +11,36 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 // -----------------------------------------------------------------------------
 
 const (
-	defaultPoolMaxConns = 32
+	defaultPoolMaxConns        = 32
+	defaultIdempotencyTable    = "idempotency_keys"
+	defaultConnectRetryBackoff = time.Second
+	maxConnectRetryBackoff     = 30 * time.Second
+	initialHealthCheckBackoff  = 100 * time.Millisecond
+	maxHealthCheckBackoff      = 2 * time.Second
+	defaultMaxErrorSqlLength   = 2048
+	prePingTimeout             = 5 * time.Second
+	initialTooManyConnsBackoff = 100 * time.Millisecond
+	maxTooManyConnsBackoff     = 2 * time.Second
 )
 
 // -----------------------------------------------------------------------------
 
 // WithinTxCallback defines a callback called in the context of the initiated transaction.
-type WithinTxCallback = func(ctx context.Context, tx Tx) error
+type WithinTxCallback = func(ctx context.Context, tx *Tx) error
 
 // WithinConnCallback defines a callback called in the context of a single connection.
-type WithinConnCallback = func(ctx context.Context, conn Conn) error
+type WithinConnCallback = func(ctx context.Context, conn *Conn) error
 
 // CopyCallback defines a callback that is called for each record being copied to the database
 type CopyCallback func(ctx context.Context, idx int) ([]interface{}, error)
@@ -38,13 +49,33 @@ type CopyCallback func(ctx context.Context, idx int) ([]interface{}, error)
 
 // Database represents a PostgreSQL database accessor.
 type Database struct {
-	pool *pgxpool.Pool
+	pool atomic.Pointer[pgxpool.Pool]
 	err  struct {
 		mutex   sync.Mutex
 		handler ErrorHandler
 		last    error
 	}
-	nameHash [32]byte
+	nameHash            [32]byte
+	trackAcquireWait    bool
+	tooManyConnsRetries int
+	debugValidate       bool
+	debugPlaceholders   bool
+	maxErrorSqlLength   int
+	defaultSchema       string
+	closedConns         atomic.Int64
+	autoPrepare         *autoPrepare
+	cacheListeners      cacheListeners
+	queryCache          QueryCache
+	typeMaps            sync.Pool
+	idempotency         struct {
+		mutex     sync.Mutex
+		tableName string
+		created   bool
+	}
+	serverVersion struct {
+		mutex sync.Mutex
+		value *ServerVersion
+	}
 }
 
 // Options defines the database connection options.
@@ -59,12 +90,133 @@ type Options struct {
 	IdleTimeout      string `json:"idleTimeout"`
 	SSLMode          SSLMode
 	ExtendedSettings map[string]string `json:"extendedSettings"`
+
+	// VerifyConnect makes New establish a connection to the server and fail if it is not reachable
+	// or the credentials are wrong. If false, connections are not established until they are needed.
+	VerifyConnect bool `json:"verifyConnect"`
+
+	// ConnectRetries sets the number of times New retries to establish the first connection to the
+	// server. A value greater than zero implies VerifyConnect.
+	ConnectRetries int `json:"connectRetries"`
+
+	// ConnectRetryBackoff sets the initial delay between connection retries. The delay is doubled
+	// after each attempt. Defaults to one second.
+	ConnectRetryBackoff string `json:"connectRetryBackoff"`
+
+	// TooManyConnectionsRetries sets the number of times a connection acquisition made by WithinConn,
+	// WithPinnedConn and similar methods is retried, with an increasing delay, if the server rejects
+	// the new connection because its max_connections limit was reached.
+	TooManyConnectionsRetries int `json:"tooManyConnectionsRetries"`
+
+	// IdempotencyTable is the name of the table used by ExecIdempotent to store the keys. Defaults to
+	// "idempotency_keys".
+	IdempotencyTable string `json:"idempotencyTable"`
+
+	// BeforeAcquire is called before a connection is acquired from the pool. If it returns false, the
+	// connection is discarded and another one is acquired.
+	BeforeAcquire func(ctx context.Context, conn *pgx.Conn) bool `json:"-"`
+
+	// AfterRelease is called after a connection is released but before it is returned to the pool.
+	// If it returns false, the connection is destroyed.
+	AfterRelease func(conn *pgx.Conn) bool `json:"-"`
+
+	// PrePing makes the pool verify each connection is alive by sending a ping before handing it
+	// out. Dead connections are transparently discarded and another one is acquired. It adds a
+	// roundtrip to the server on every acquire, so use it only if connections are frequently
+	// dropped, for e.g. by unstable networks or aggressive firewalls.
+	PrePing bool `json:"prePing"`
+
+	// OnConnect is called after a new connection is established but before it is added to the pool.
+	// If it returns an error, the connection is closed and the error is returned to the caller
+	// that requested it.
+	OnConnect func(ctx context.Context, conn *pgx.Conn) error `json:"-"`
+
+	// OnDisconnect is called right before a connection is closed and removed from the pool.
+	OnDisconnect func(conn *pgx.Conn) `json:"-"`
+
+	// OnAcquireWait is called with the time spent waiting for a connection from the pool.
+	OnAcquireWait func(d time.Duration) `json:"-"`
+
+	// ConnLeakThreshold enables the leaked connection detector. If a connection is held longer than
+	// the specified duration, OnConnLeak is called with the stack of the goroutine that acquired it.
+	// Intended for debugging purposes only because it captures a stack trace on every acquire.
+	ConnLeakThreshold time.Duration `json:"connLeakThreshold"`
+
+	// OnConnLeak is called when a connection is held longer than ConnLeakThreshold.
+	OnConnLeak ConnLeakHandler `json:"-"`
+
+	// DebugValidate makes Exec and Query methods ask the server to parse and analyze each statement
+	// before executing it in order to report syntax and type errors along with the offending
+	// statement. It doubles the number of roundtrips so it is intended for development only.
+	DebugValidate bool `json:"debugValidate"`
+
+	// DebugPlaceholders makes Exec and Query methods verify the highest $N placeholder referenced by
+	// each statement matches the number of provided arguments before sending it to the server.
+	DebugPlaceholders bool `json:"debugPlaceholders"`
+
+	// MaxErrorSqlLength sets the maximum length, in bytes, of the SQL sentences included in error
+	// messages. Longer sentences are truncated and an ellipsis, included in the limit, is appended.
+	// Defaults to 2048.
+	MaxErrorSqlLength int `json:"maxErrorSqlLength"`
+
+	// QueryCache sets the storage used by QueryRowsCached.
+	QueryCache QueryCache `json:"-"`
+
+	// StatementTimeout sets the `statement_timeout` runtime parameter on every connection so the
+	// server aborts any statement running longer than the specified duration. It can be overridden
+	// per transaction with `SET LOCAL statement_timeout`. Zero means no limit.
+	StatementTimeout time.Duration `json:"statementTimeout"`
+
+	// DefaultSchema sets the schema prepended to unqualified table names passed to helpers like
+	// Copy, CopyStructs, CopyFromCSV, InsertUnnest, BulkInsertReturning, LockRow and
+	// GetByKeysOrdered. Table names containing a dot are considered schema-qualified and the default
+	// schema is not applied. Queries written by hand are not affected.
+	DefaultSchema string `json:"defaultSchema"`
+
+	// AutoPrepareThreshold enables the automatic preparation of frequently executed statements. When
+	// set, statements are sent without being prepared until the same SQL sentence is executed the
+	// specified number of times. From then on, it is prepared and cached on each connection of the
+	// pool. Zero keeps the default behavior of preparing every statement.
+	//
+	// Each auto-prepared statement consumes memory on the server for every pooled connection, so the
+	// number of auto-prepared statements is limited by AutoPrepareMaxStatements.
+	//
+	// NOTE: This changes how every statement is sent, not only the frequently executed ones. Below the
+	// threshold, statements run using QueryExecModeExec, so parameter types are inferred from the Go
+	// types of the arguments instead of being described by the server. Add explicit casts, for e.g.
+	// `$1::int`, where the inferred type is not the expected one.
+	AutoPrepareThreshold int `json:"autoPrepareThreshold"`
+
+	// AutoPrepareMaxStatements sets the maximum number of auto-prepared statements. Defaults to 256.
+	AutoPrepareMaxStatements int `json:"autoPrepareMaxStatements"`
+
+	// IdleInTransactionTimeout sets the `idle_in_transaction_session_timeout` runtime parameter on
+	// every connection so the server terminates sessions that stay idle within an open transaction
+	// longer than the specified duration, releasing the locks they hold. Within WithinTx, this
+	// applies to the time spent in the callback between statements, so avoid doing long non-database
+	// work there. The affected connection is closed and subsequent operations in the transaction
+	// fail. Zero means no limit.
+	IdleInTransactionTimeout time.Duration `json:"idleInTransactionTimeout"`
 }
 
 // WithinTxOptions defines some transaction options
 type WithinTxOptions struct {
 	ReadOnly       bool
 	RepeatableRead bool
+
+	// DeferConstraints executes `SET CONSTRAINTS ALL DEFERRED` at the beginning of the transaction
+	// so constraints are checked on commit. Only constraints declared as DEFERRABLE are affected.
+	DeferConstraints bool
+}
+
+// CommandTag contains details about an executed command.
+type CommandTag struct {
+	RowsAffected int64
+	Tag          string
+	Insert       bool
+	Update       bool
+	Delete       bool
+	Select       bool
 }
 
 // ErrorHandler defines a custom error handler.
@@ -103,10 +255,34 @@ func New(ctx context.Context, opts Options) (*Database, error) {
 	default:
 		return nil, errors.New("invalid SSL mode")
 	}
+	for k := range opts.ExtendedSettings {
+		if !isValidExtendedSettingKey(k) {
+			return nil, errors.New("invalid extended setting name [name=" + k + "]")
+		}
+	}
 
 	// Create database object
 	db := Database{}
 	db.err.mutex = sync.Mutex{}
+	db.idempotency.mutex = sync.Mutex{}
+	db.serverVersion.mutex = sync.Mutex{}
+	db.queryCache = opts.QueryCache
+	db.debugValidate = opts.DebugValidate
+	db.debugPlaceholders = opts.DebugPlaceholders
+	db.maxErrorSqlLength = defaultMaxErrorSqlLength
+	if opts.MaxErrorSqlLength > 0 {
+		db.maxErrorSqlLength = opts.MaxErrorSqlLength
+	}
+	db.defaultSchema = opts.DefaultSchema
+	db.typeMaps = sync.Pool{
+		New: func() interface{} {
+			return pgtype.NewMap()
+		},
+	}
+	db.idempotency.tableName = defaultIdempotencyTable
+	if len(opts.IdempotencyTable) > 0 {
+		db.idempotency.tableName = opts.IdempotencyTable
+	}
 
 	// Create a hash of the database name
 	h := sha256.New()
@@ -124,8 +300,9 @@ func New(ctx context.Context, opts Options) (*Database, error) {
 		for k, v := range opts.ExtendedSettings {
 			_, _ = sbConnString.WriteRune(' ')
 			_, _ = sbConnString.WriteString(k)
-			_, _ = sbConnString.WriteRune('=')
+			_, _ = sbConnString.WriteString("='")
 			_, _ = sbConnString.WriteString(encodeDSN(v))
+			_, _ = sbConnString.WriteRune('\'')
 		}
 	}
 	poolConfig, err := pgxpool.ParseConfig(sbConnString.String())
@@ -162,13 +339,88 @@ func New(ctx context.Context, opts Options) (*Database, error) {
 			poolConfig.MaxConnIdleTime = 10 * time.Second
 		}
 	}
+	if opts.StatementTimeout < 0 {
+		return nil, errors.New("invalid statement timeout value")
+	}
+	if opts.StatementTimeout > 0 {
+		poolConfig.ConnConfig.RuntimeParams["statement_timeout"] = formatTimeoutParam(opts.StatementTimeout)
+	}
+	if opts.IdleInTransactionTimeout < 0 {
+		return nil, errors.New("invalid idle in transaction timeout value")
+	}
+	if opts.IdleInTransactionTimeout > 0 {
+		poolConfig.ConnConfig.RuntimeParams["idle_in_transaction_session_timeout"] = formatTimeoutParam(
+			opts.IdleInTransactionTimeout,
+		)
+	}
+	if opts.AutoPrepareThreshold > 0 {
+		db.autoPrepare = newAutoPrepare(opts.AutoPrepareThreshold, opts.AutoPrepareMaxStatements)
+		poolConfig.ConnConfig.DefaultQueryExecMode = pgx.QueryExecModeExec
+		poolConfig.ConnConfig.StatementCacheCapacity = db.autoPrepare.maxStatements
+	}
+	connectRetryBackoff := defaultConnectRetryBackoff
+	if len(opts.ConnectRetryBackoff) > 0 {
+		connectRetryBackoff, err = time.ParseDuration(opts.ConnectRetryBackoff)
+		if err != nil || connectRetryBackoff <= 0 {
+			return nil, errors.New("invalid connection retry backoff value")
+		}
+	}
+	if opts.TooManyConnectionsRetries < 0 {
+		return nil, errors.New("invalid too many connections retries count")
+	}
+	db.tooManyConnsRetries = opts.TooManyConnectionsRetries
+	poolConfig.BeforeAcquire = opts.BeforeAcquire
+	if opts.PrePing {
+		poolConfig.BeforeAcquire = wrapPrePing(poolConfig.BeforeAcquire)
+	}
+	poolConfig.AfterRelease = opts.AfterRelease
+	poolConfig.AfterConnect = opts.OnConnect
+	onDisconnect := opts.OnDisconnect
+	poolConfig.BeforeClose = func(conn *pgx.Conn) {
+		db.closedConns.Add(1)
+		if onDisconnect != nil {
+			onDisconnect(conn)
+		}
+	}
+	if opts.OnAcquireWait != nil {
+		beforeAcquire := poolConfig.BeforeAcquire
+		onAcquireWait := opts.OnAcquireWait
+
+		db.trackAcquireWait = true
+		poolConfig.BeforeAcquire = func(ctx context.Context, conn *pgx.Conn) bool {
+			if beforeAcquire != nil && !beforeAcquire(ctx, conn) {
+				return false
+			}
+			if startTime, ok := getAcquireStartTime(ctx); ok {
+				onAcquireWait(time.Since(startTime))
+			}
+			return true
+		}
+	}
+	if opts.ConnLeakThreshold > 0 && opts.OnConnLeak != nil {
+		leakDetector := newConnLeakDetector(opts.ConnLeakThreshold, opts.OnConnLeak)
+		poolConfig.BeforeAcquire = leakDetector.wrapBeforeAcquire(poolConfig.BeforeAcquire)
+		poolConfig.AfterRelease = leakDetector.wrapAfterRelease(poolConfig.AfterRelease)
+		poolConfig.BeforeClose = leakDetector.wrapBeforeClose(poolConfig.BeforeClose)
+	}
+	poolConfig.BeforeAcquire = wrapConnAcquired(poolConfig.BeforeAcquire)
 
 	// Create the database connection pool
-	db.pool, err = pgxpool.NewWithConfig(ctx, poolConfig)
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
 	if err != nil {
 		db.Close()
 		return nil, errors.New("unable to initialize database connection pool")
 	}
+	db.pool.Store(pool)
+
+	// Establish the first connection if requested
+	if opts.VerifyConnect || opts.ConnectRetries > 0 {
+		err = db.connectWithRetry(ctx, opts.ConnectRetries, connectRetryBackoff)
+		if err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
 
 	// Done
 	return &db, nil
@@ -268,6 +520,62 @@ func NewFromURL(ctx context.Context, rawUrl string) (*Database, error) {
 		case "idletimeout":
 			opts.IdleTimeout = v
 
+		case "verifyconnect":
+			if len(v) > 0 {
+				val, err2 := strconv.ParseBool(v)
+				if err2 != nil {
+					return nil, errors.New("invalid verify connect value")
+				}
+				opts.VerifyConnect = val
+			}
+		case "connectretries":
+			if len(v) > 0 {
+				val, err2 := strconv.Atoi(v)
+				if err2 != nil || val < 0 {
+					return nil, errors.New("invalid connection retries count")
+				}
+				opts.ConnectRetries = val
+			}
+		case "connectretrybackoff":
+			opts.ConnectRetryBackoff = v
+		case "toomanyconnectionsretries":
+			if len(v) > 0 {
+				val, err2 := strconv.Atoi(v)
+				if err2 != nil || val < 0 {
+					return nil, errors.New("invalid too many connections retries count")
+				}
+				opts.TooManyConnectionsRetries = val
+			}
+
+		case "preping":
+			if len(v) > 0 {
+				val, err2 := strconv.ParseBool(v)
+				if err2 != nil {
+					return nil, errors.New("invalid pre-ping value")
+				}
+				opts.PrePing = val
+			}
+
+		case "statementtimeout":
+			if len(v) > 0 {
+				val, err2 := time.ParseDuration(v)
+				if err2 != nil || val < 0 {
+					return nil, errors.New("invalid statement timeout value")
+				}
+				opts.StatementTimeout = val
+			}
+		case "idleintransactiontimeout":
+			if len(v) > 0 {
+				val, err2 := time.ParseDuration(v)
+				if err2 != nil || val < 0 {
+					return nil, errors.New("invalid idle in transaction timeout value")
+				}
+				opts.IdleInTransactionTimeout = val
+			}
+
+		case "defaultschema":
+			opts.DefaultSchema = v
+
 		case "":
 
 		default:
@@ -285,11 +593,112 @@ func NewFromURL(ctx context.Context, rawUrl string) (*Database, error) {
 
 // Close shutdown the connection pool
 func (db *Database) Close() {
-	if db.pool != nil {
-		db.pool.Close()
-		db.pool = nil
+	db.stopCacheListeners()
+	pool := db.pool.Swap(nil)
+	if pool != nil {
+		pool.Close()
+	}
+	db.SetEventHandler(nil)
+}
+
+// CloseWithTimeout shuts down the connection pool like Close does but stops waiting for the
+// connections to be returned if the context expires. In that case, an error is returned and the
+// pool will finish closing in the background once all connections are released.
+func (db *Database) CloseWithTimeout(ctx context.Context) error {
+	db.stopCacheListeners()
+	pool := db.pool.Swap(nil)
+	if pool != nil {
+		done := make(chan struct{})
+		go func() {
+			pool.Close()
+			close(done)
+		}()
+
+		select {
+		case <-done:
+		case <-ctx.Done():
+			db.SetEventHandler(nil)
+			return newError(ctx.Err(), "timeout waiting for connections to be released")
+		}
 	}
 	db.SetEventHandler(nil)
+
+	// Done
+	return nil
+}
+
+// SetMaxConns changes the maximum number of connections of the pool.
+//
+// The underlying library does not support resizing a pool so a new one is created with the same
+// settings and the new limit. New operations use the new pool while in-flight ones complete using
+// the old pool, which is closed in the background once all its connections are released. An
+// operation that retrieved the old pool right before the swap may fail to acquire a connection.
+func (db *Database) SetMaxConns(n int32) error {
+	if n < 1 {
+		return errors.New("invalid max connections value")
+	}
+
+	oldPool := db.pool.Load()
+	if oldPool == nil {
+		return errors.New("database is closed")
+	}
+	poolConfig := oldPool.Config()
+	if poolConfig.MaxConns == n {
+		return nil
+	}
+	poolConfig.MaxConns = n
+	if poolConfig.MinConns > n {
+		poolConfig.MinConns = n
+	}
+
+	newPool, err := pgxpool.NewWithConfig(context.Background(), poolConfig)
+	if err != nil {
+		return errors.New("unable to initialize database connection pool")
+	}
+	if !db.pool.CompareAndSwap(oldPool, newPool) {
+		newPool.Close()
+		return errors.New("database pool was concurrently modified")
+	}
+
+	// Drain the old pool
+	go oldPool.Close()
+
+	// Done
+	return nil
+}
+
+// WaitHealthy waits until the database server responds or the timeout elapses. Useful in recovery
+// paths, for example, after receiving an ErrorTypeConnection error during a server failover.
+//
+// The server is polled with an increasing delay between attempts. Intermediate failures are not
+// reported to the error handler.
+func (db *Database) WaitHealthy(ctx context.Context, timeout time.Duration) error {
+	pool := db.pool.Load()
+	if pool == nil {
+		return errors.New("database is closed")
+	}
+
+	ctx, cancelCtx := context.WithTimeout(ctx, timeout)
+	defer cancelCtx()
+
+	backoff := initialHealthCheckBackoff
+	for {
+		err := pool.Ping(ctx)
+		if err == nil {
+			return nil
+		}
+
+		// Wait before retrying
+		select {
+		case <-ctx.Done():
+			return db.handleError(ctx, newError(err, "database server is not healthy"))
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > maxHealthCheckBackoff {
+			backoff = maxHealthCheckBackoff
+		}
+	}
 }
 
 // SetEventHandler sets a new error handler callback
@@ -302,14 +711,44 @@ func (db *Database) SetEventHandler(handler ErrorHandler) {
 
 // Exec executes an SQL statement on a new connection
 func (db *Database) Exec(ctx context.Context, sql string, args ...interface{}) (int64, error) {
+	pinnedConn := db.getPinnedConn(ctx)
+	if pinnedConn != nil {
+		return pinnedConn.Exec(ctx, sql, args...)
+	}
+	ctx = db.withAcquireTracking(ctx)
+	err := db.validateExecSql(ctx, nil, sql, args)
+	if err != nil {
+		return 0, db.handleError(ctx, err)
+	}
 	affectedRows := int64(0)
-	ct, err := db.pool.Exec(ctx, sql, args...)
+	ct, err := db.pool.Load().Exec(ctx, sql, db.withQueryExecMode(ctx, sql, args)...)
 	if err == nil {
 		affectedRows = ct.RowsAffected()
 	} else {
-		err = newError(err, "unable to execute command")
+		err = db.newAcquireError(ctx, err, "unable to execute command")
 	}
-	return affectedRows, db.handleError(err)
+	return affectedRows, db.handleError(ctx, err)
+}
+
+// ExecTag executes an SQL statement on a new connection and returns the command tag details
+func (db *Database) ExecTag(ctx context.Context, sql string, args ...interface{}) (CommandTag, error) {
+	pinnedConn := db.getPinnedConn(ctx)
+	if pinnedConn != nil {
+		return pinnedConn.ExecTag(ctx, sql, args...)
+	}
+	ctx = db.withAcquireTracking(ctx)
+	err := db.validateExecSql(ctx, nil, sql, args)
+	if err != nil {
+		return CommandTag{}, db.handleError(ctx, err)
+	}
+	tag := CommandTag{}
+	ct, err := db.pool.Load().Exec(ctx, sql, db.withQueryExecMode(ctx, sql, args)...)
+	if err == nil {
+		tag = newCommandTag(ct)
+	} else {
+		err = db.newAcquireError(ctx, err, "unable to execute command")
+	}
+	return tag, db.handleError(ctx, err)
 }
 
 // QueryRow executes a SQL query on a new connection
@@ -319,33 +758,77 @@ func (db *Database) Exec(ctx context.Context, sql string, args ...interface{}) (
 //  1. Most of the commonly used types in Postgres can be mapped to standard Golang type including
 //     time.Time for timestamps (except time with tz which is not supported)
 //  2. When reading JSON/JSONB fields, the underlying library (PGX) tries to unmarshall it into the
-//     destination variable. In order to just retrieve the json string, add the `::text` suffix to
-//     the field in the query.
+//     destination variable. Fields with a dynamic structure can be read directly into a
+//     map[string]interface{} or []interface{} variable. In order to just retrieve the json string,
+//     add the `::text` suffix to the field in the query.
 //  3. To avoid overflows on high uint64 values, store them in NUMERIC(24,0) fields.
 //  4. For time-only fields, date is set to Jan 1, 2000 by PGX in time.Time variables.
+//  5. Interval fields can be read into time.Duration variables assuming months have 30 days. Use
+//     the Interval type to get the raw months, days and microseconds components.
 func (db *Database) QueryRow(ctx context.Context, sql string, args ...interface{}) Row {
+	pinnedConn := db.getPinnedConn(ctx)
+	if pinnedConn != nil {
+		return pinnedConn.QueryRow(ctx, sql, args...)
+	}
+	ctx = db.withAcquireTracking(ctx)
+	err := db.validateSql(ctx, nil, sql, args)
+	if err != nil {
+		return &rowGetter{
+			ctx: ctx,
+			db:  db,
+			err: err,
+		}
+	}
+	rows, err := db.pool.Load().Query(ctx, sql, db.withQueryExecMode(ctx, sql, args)...)
 	return &rowGetter{
-		db:  db,
-		row: db.pool.QueryRow(ctx, sql, args...),
+		ctx:  ctx,
+		db:   db,
+		rows: rows,
+		err:  db.newAcquireError(ctx, err, "unable to scan row"),
 	}
 }
 
 // QueryRows executes a SQL query on a new connection
 func (db *Database) QueryRows(ctx context.Context, sql string, args ...interface{}) Rows {
-	rows, err := db.pool.Query(ctx, sql, args...)
+	pinnedConn := db.getPinnedConn(ctx)
+	if pinnedConn != nil {
+		return pinnedConn.QueryRows(ctx, sql, args...)
+	}
+	ctx = db.withAcquireTracking(ctx)
+	err := db.validateSql(ctx, nil, sql, args)
+	if err != nil {
+		return &rowsGetter{
+			db:  db,
+			ctx: ctx,
+			err: err,
+		}
+	}
+	rows, err := db.pool.Load().Query(ctx, sql, db.withQueryExecMode(ctx, sql, args)...)
 	return &rowsGetter{
 		db:   db,
 		ctx:  ctx,
 		rows: rows,
-		err:  newError(err, "unable to run query"),
+		err:  db.newAcquireError(ctx, err, "unable to run query"),
 	}
 }
 
-// Copy executes a SQL copy query within the transaction.
+// Copy executes a SQL copy query on a new connection.
+//
+// A table name containing a dot, for e.g. "audit.events", is split at the first dot into schema and
+// table, else the DefaultSchema is used.
+//
+// If the context is cancelled or its deadline is exceeded while copying, the callback is not called
+// anymore and the COPY is aborted. Because COPY is atomic, no rows are inserted in that case and the
+// context error is returned.
 func (db *Database) Copy(ctx context.Context, tableName string, columnNames []string, cb CopyCallback) (int64, error) {
-	n, err := db.pool.CopyFrom(
+	pinnedConn := db.getPinnedConn(ctx)
+	if pinnedConn != nil {
+		return pinnedConn.Copy(ctx, tableName, columnNames, cb)
+	}
+	ctx = db.withAcquireTracking(ctx)
+	n, err := db.pool.Load().CopyFrom(
 		ctx,
-		pgx.Identifier{tableName},
+		db.getTableIdentifier(tableName),
 		columnNames,
 		&copyWithCallback{
 			ctx: ctx,
@@ -354,35 +837,36 @@ func (db *Database) Copy(ctx context.Context, tableName string, columnNames []st
 	)
 
 	// Done
-	return n, db.handleError(newError(err, "unable to execute command"))
+	return n, db.handleError(ctx, db.newAcquireError(ctx, err, "unable to execute command"))
 }
 
 // WithinTx executes a callback function within the context of a transaction
 func (db *Database) WithinTx(ctx context.Context, cb WithinTxCallback, opts ...WithinTxOptions) error {
-	txOpts := pgx.TxOptions{
-		IsoLevel:       pgx.ReadCommitted,
-		AccessMode:     pgx.ReadWrite,
-		DeferrableMode: pgx.NotDeferrable,
-	}
-	if len(opts) > 0 {
-		if opts[0].ReadOnly {
-			txOpts.AccessMode = pgx.ReadOnly
-		}
-		if opts[0].RepeatableRead {
-			txOpts.IsoLevel = pgx.RepeatableRead
-		}
+	pinnedConn := db.getPinnedConn(ctx)
+	if pinnedConn != nil {
+		return pinnedConn.WithinTx(ctx, cb, opts...)
 	}
-
-	tx, err := db.pool.BeginTx(ctx, txOpts)
+	ctx = db.withAcquireTracking(ctx)
+	tx, err := db.pool.Load().BeginTx(ctx, getTxOptions(opts))
 	if err == nil {
-		err = cb(ctx, Tx{
-			db: db,
-			tx: tx,
+		hooks := newTxHooks(nil)
+		stats := newTxStats()
+		ctx = stats.attach(hooks.attach(ctx))
+		err = cb(ctx, &Tx{
+			db:    db,
+			tx:    tx,
+			hooks: hooks,
+			stats: stats,
 		})
 		if err == nil {
-			err = tx.Commit(ctx)
-			if err != nil {
-				err = newError(err, "unable to commit db transaction")
+			err = hooks.runBeforeCommit(ctx)
+			if err == nil {
+				err = tx.Commit(ctx)
+				if err != nil {
+					err = newError(err, "unable to commit db transaction")
+				}
+			} else {
+				err = newError(err, "before commit callback returned failure")
 			}
 		} else {
 			err = newError(err, "callback returned failure")
@@ -390,17 +874,68 @@ func (db *Database) WithinTx(ctx context.Context, cb WithinTxCallback, opts ...W
 		if err != nil {
 			_ = tx.Rollback(context.Background()) // Using context.Background() on purpose
 		}
+		hooks.finish(err == nil)
+	} else {
+		err = db.newAcquireError(ctx, err, "unable to start transaction")
+	}
+	return db.handleError(ctx, err)
+}
+
+// WithinTxAs executes a callback function within the context of a transaction that runs with the
+// privileges of the given role by executing `SET LOCAL ROLE` at the beginning.
+func (db *Database) WithinTxAs(ctx context.Context, role string, cb WithinTxCallback, opts ...WithinTxOptions) error {
+	return db.WithinTx(ctx, func(ctx context.Context, tx *Tx) error {
+		_, err := tx.Exec(ctx, "SET LOCAL ROLE "+QuoteIdentifier(role))
+		if err != nil {
+			return err
+		}
+		return cb(ctx, tx)
+	}, opts...)
+}
+
+// Begin starts a new transaction that must be finished by calling Tx.Commit or Tx.Rollback.
+//
+// WithinTx is the recommended way to execute transactions. Use this method only if the control
+// flow does not fit in a callback. The connection is returned to the pool when the transaction ends
+// unless it is a pinned one. Use Tx.Context to register commit and rollback hooks.
+func (db *Database) Begin(ctx context.Context, opts ...WithinTxOptions) (*Tx, error) {
+	var tx pgx.Tx
+	var err error
+
+	pinnedConn := db.getPinnedConn(ctx)
+	if pinnedConn != nil {
+		tx, err = pinnedConn.conn.BeginTx(ctx, getTxOptions(opts))
+		if err != nil {
+			return nil, db.handleError(ctx, newError(err, "unable to start transaction"))
+		}
 	} else {
-		err = newError(err, "unable to start transaction")
+		ctx = db.withAcquireTracking(ctx)
+		tx, err = db.pool.Load().BeginTx(ctx, getTxOptions(opts))
+		if err != nil {
+			return nil, db.handleError(ctx, db.newAcquireError(ctx, err, "unable to start transaction"))
+		}
 	}
-	return db.handleError(err)
+
+	// Done
+	return &Tx{
+		db:       db,
+		tx:       tx,
+		hooks:    newTxHooks(nil),
+		stats:    newTxStats(),
+		explicit: true,
+	}, nil
 }
 
 // WithinConn executes a callback function within the context of a single connection
 func (db *Database) WithinConn(ctx context.Context, cb WithinConnCallback) error {
-	conn, err := db.pool.Acquire(ctx)
+	pinnedConn := db.getPinnedConn(ctx)
+	if pinnedConn != nil {
+		return db.handleError(ctx, newError(cb(ctx, pinnedConn), "callback returned failure"))
+	}
+	ctx = db.withAcquireTracking(ctx)
+	conn, err := db.acquireConn(ctx)
 	if err == nil {
-		err = cb(ctx, Conn{
+		err = cb(ctx, &Conn{
 			db:   db,
 			conn: conn,
 		})
@@ -409,7 +944,82 @@ func (db *Database) WithinConn(ctx context.Context, cb WithinConnCallback) error
 		}
 		conn.Release()
 	} else {
-		err = newError(err, "unable to acquire a connection from the pool")
+		err = db.newAcquireError(ctx, err, "unable to acquire a connection from the pool")
 	}
-	return db.handleError(err)
+	return db.handleError(ctx, err)
+}
+
+// AsRole executes a callback function within the context of a single connection that runs with
+// the privileges of the given role by executing `SET ROLE` before calling it. `RESET ROLE` is
+// executed afterward, even if the callback fails, so the connection is returned to the pool with
+// the original privileges. If the role cannot be reset, the connection is closed.
+//
+// Use WithinTxAs if the work must also run within a transaction.
+func (db *Database) AsRole(ctx context.Context, role string, cb WithinConnCallback) error {
+	return db.WithinConn(ctx, func(ctx context.Context, conn *Conn) error {
+		_, err := conn.Exec(ctx, "SET ROLE "+QuoteIdentifier(role))
+		if err != nil {
+			return err
+		}
+		defer func() {
+			_, resetErr := conn.Exec(context.Background(), "RESET ROLE") // Using context.Background() on purpose
+			if resetErr != nil {
+				// Do not return a connection with elevated or restricted privileges to the pool
+				_ = conn.conn.Conn().Close(context.Background())
+			}
+		}()
+
+		return cb(ctx, conn)
+	})
+}
+
+// WithTempTable executes a callback function within the context of a single connection after
+// creating a temporary table with the provided DDL sentence. Temporary tables only exist within
+// the connection that created them so they must be accessed through the provided Conn object.
+//
+// All the temporary tables of the connection are dropped before it is returned to the pool.
+func (db *Database) WithTempTable(ctx context.Context, ddl string, cb WithinConnCallback) error {
+	return db.WithinConn(ctx, func(ctx context.Context, conn *Conn) error {
+		defer func() {
+			_, _ = conn.Exec(context.Background(), "DISCARD TEMP") // Using context.Background() on purpose
+		}()
+
+		_, err := conn.Exec(ctx, ddl)
+		if err != nil {
+			return err
+		}
+		return cb(ctx, conn)
+	})
+}
+
+// ExecAs executes an SQL statement on a single connection that reports the given label as its
+// application_name, so the operation can be identified in pg_stat_activity. The original
+// application name is restored before the connection is returned to the pool.
+func (db *Database) ExecAs(ctx context.Context, label string, sql string, args ...interface{}) (int64, error) {
+	var affectedRows int64
+
+	err := db.WithinConn(ctx, func(ctx context.Context, conn *Conn) error {
+		var appName string
+
+		err := conn.QueryRow(ctx, `SELECT current_setting('application_name')`).Scan(&appName)
+		if err != nil {
+			return err
+		}
+		_, err = conn.Exec(ctx, `SELECT set_config('application_name', $1, false)`, label)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			_, _ = conn.Exec(context.Background(), `SELECT set_config('application_name', $1, false)`, appName) // Using context.Background() on purpose
+		}()
+
+		affectedRows, err = conn.Exec(ctx, sql, args...)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	// Done
+	return affectedRows, nil
 }
@@ -3,13 +3,16 @@
 package postgres_test
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"flag"
 	"fmt"
 	"math"
 	"reflect"
+	"sort"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -117,6 +120,217 @@ func TestPostgres(t *testing.T) {
 	if err != nil {
 		t.Fatalf("%v", err.Error())
 	}
+
+	t.Log("Reading test data (lenient)")
+	err = readLenientTestData(ctx, db)
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+
+	t.Log("Reading test data by column name")
+	err = readByNameTestData(ctx, db)
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+
+	t.Log("Reading JSON data into maps and slices")
+	err = readJSONTestData(ctx, db)
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+
+	t.Log("Testing constraint violation errors")
+	err = testConstraintErrors(ctx, db)
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+
+	t.Log("Importing CSV data")
+	err = testCopyFromCSV(ctx, db)
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+
+	t.Log("Exporting CSV data")
+	err = testQueryCSV(ctx, db)
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+
+	t.Log("Streaming rows through a channel")
+	err = testQueryChan(ctx, db)
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+
+	t.Log("Checking server version")
+	err = testServerVersion(ctx, db)
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+
+	t.Log("Checking transaction statistics in errors")
+	err = testTxStats(ctx, db)
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+
+	t.Log("Locking rows")
+	err = testLockRow(ctx, db)
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+
+	t.Log("Locking rows concurrently")
+	err = testLockRowConcurrent(ctx, db)
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+
+	t.Log("Reading rows by keys")
+	err = testGetByKeysOrdered(ctx, db)
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+
+	t.Log("Reading raw row values")
+	err = testRowValues(ctx, db)
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+
+	t.Log("Checking undefined object errors")
+	err = testUndefinedObjectError(ctx, db)
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+
+	t.Log("Explaining queries")
+	err = testExplain(ctx, db)
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+
+	t.Log("Cancelling a copy")
+	err = testCopyCancellation(ctx, db)
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+
+	t.Log("Resetting a connection")
+	err = testConnReset(ctx, db)
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+
+	t.Log("Mapping rows")
+	err = testQueryRowsMapped(ctx, db)
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+
+	t.Log("Running as another role")
+	err = testAsRole(ctx, db)
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+
+	t.Log("Running a transaction as another role")
+	err = testWithinTxAs(ctx, db)
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+
+	t.Log("Bulk inserting rows ignoring conflicts")
+	err = testBulkInsertIgnore(ctx, db)
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+
+	t.Log("Updating rows from a map")
+	err = testUpdateMap(ctx, db)
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+
+	t.Log("Running scripts")
+	err = testRunScript(ctx, db)
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+
+	t.Log("Executing idempotent statements")
+	err = testExecIdempotent(ctx, db)
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+
+	t.Log("Running once")
+	err = testOnce(ctx, db)
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+
+	t.Log("Calling set-returning functions")
+	err = testSetReturningFunctions(ctx, db)
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+
+	t.Log("Resizing the pool")
+	err = testSetMaxConns(ctx, db)
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+
+	t.Log("Reading rows using a cursor")
+	err = testQueryCursor(ctx, db)
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+
+	t.Log("Pinning a connection")
+	err = testPinnedConn(ctx, db)
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+
+	t.Log("Exporting rows as JSON lines")
+	err = testQueryJSONLines(ctx, db)
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+
+	t.Log("Loading related rows")
+	err = testLoadRelated(ctx, db)
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+
+	t.Log("Checking pool statistics")
+	stats := db.PoolStats()
+	if stats.NewConnsCount == 0 || stats.AcquireCount == 0 {
+		t.Fatalf("unexpected pool statistics [stats=%+v]", stats)
+	}
+
+	t.Log("Testing transaction hooks")
+	err = testTxHooks(ctx, db)
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+
+	t.Log("Testing explicit transactions")
+	err = testExplicitTx(ctx, db)
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+
+	// NOTE: This must be the last step because it closes the database.
+	t.Log("Closing with timeout")
+	err = testCloseWithTimeout(ctx, db)
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
 }
 
 // -----------------------------------------------------------------------------
@@ -157,7 +371,7 @@ func createTestTable(ctx context.Context, db *postgres.Database) error {
 }
 
 func insertTestData(ctx context.Context, db *postgres.Database) error {
-	return db.WithinTx(ctx, func(ctx context.Context, tx postgres.Tx) error {
+	return db.WithinTx(ctx, func(ctx context.Context, tx *postgres.Tx) error {
 		for idx := 1; idx <= 2; idx++ {
 			rd := genTestRowDef(idx, true)
 			err := insertTestRowDef(ctx, tx, rd)
@@ -229,6 +443,1247 @@ func readMultiTestData(ctx context.Context, db *postgres.Database) error {
 	return nil
 }
 
+func readLenientTestData(ctx context.Context, db *postgres.Database) error {
+	rd := TestRowDef{}
+	err := postgres.ScanLenient(db.QueryRow(ctx, `
+		SELECT
+			id, num, sm, bi, bi2, dbl, va, chr, txt, ts, b
+		FROM
+			go_postgres_test_table
+		WHERE
+			id = 102
+	`), &rd.id, &rd.num, &rd.sm, &rd.bi, &rd.bi2, &rd.dbl, &rd.va, &rd.chr, &rd.txt, &rd.ts, &rd.b)
+	if err != nil {
+		return fmt.Errorf("unable to read test data [err=%v]", err.Error())
+	}
+
+	// NULL values must be returned as zero values
+	compareRd := TestRowDef{
+		id:  102,
+		txt: veryLongText,
+		ts:  time.Date(2022, 12, 31, 23, 59, 59, 0, time.UTC),
+	}
+	if !reflect.DeepEqual(compareRd, rd) {
+		return errors.New("data mismatch")
+	}
+
+	// Done
+	return nil
+}
+
+func readByNameTestData(ctx context.Context, db *postgres.Database) error {
+	var id int
+	var txt string
+
+	err := db.QueryRow(ctx, `SELECT id, num, txt FROM go_postgres_test_table WHERE id = 1`).ScanByName(
+		map[string]interface{}{
+			"txt": &txt,
+			"id":  &id,
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("unable to read test data by name [err=%v]", err.Error())
+	}
+	if id != 1 || txt != veryLongText {
+		return errors.New("data mismatch")
+	}
+
+	// Done
+	return nil
+}
+
+func readJSONTestData(ctx context.Context, db *postgres.Database) error {
+	var m map[string]interface{}
+	var a []interface{}
+
+	err := db.QueryRow(ctx, `SELECT js FROM go_postgres_test_table WHERE id = 1`).Scan(&m)
+	if err != nil {
+		return fmt.Errorf("unable to read json into a map [err=%v]", err.Error())
+	}
+	if !reflect.DeepEqual(m, map[string]interface{}{"id": float64(testJSON.Id), "text": testJSON.Text}) {
+		return errors.New("json map mismatch")
+	}
+
+	err = db.QueryRow(ctx, `SELECT '[1, "two", null]'::jsonb`).Scan(&a)
+	if err != nil {
+		return fmt.Errorf("unable to read json into a slice [err=%v]", err.Error())
+	}
+	if !reflect.DeepEqual(a, []interface{}{float64(1), "two", nil}) {
+		return errors.New("json slice mismatch")
+	}
+
+	// Done
+	return nil
+}
+
+func testConstraintErrors(ctx context.Context, db *postgres.Database) error {
+	_, err := db.Exec(ctx, `INSERT INTO go_postgres_test_table (id) VALUES (1)`)
+	if postgres.ConstraintKindOf(err) != postgres.ConstraintKindUnique {
+		return fmt.Errorf("expected unique constraint violation [err=%v]", err)
+	}
+
+	_, err = db.Exec(ctx, `INSERT INTO go_postgres_test_table (id) VALUES (NULL)`)
+	if postgres.ConstraintKindOf(err) != postgres.ConstraintKindNotNull {
+		return fmt.Errorf("expected not-null constraint violation [err=%v]", err)
+	}
+
+	// Done
+	return nil
+}
+
+func testCopyFromCSV(ctx context.Context, db *postgres.Database) error {
+	var count int
+
+	_, err := db.Exec(ctx, `DELETE FROM go_postgres_test_table WHERE id >= 301 AND id <= 399`)
+	if err != nil {
+		return err
+	}
+
+	n, err := db.CopyFromCSV(ctx, "go_postgres_test_table", strings.NewReader(
+		"id;sm;dbl;va;ts;b\n"+
+			"301;10;1.5;first;2022-12-31 23:59:59;true\n"+
+			"302;NULL;NULL;second;NULL;false\n",
+	), postgres.CSVOptions{
+		Delimiter: ';',
+		Header:    true,
+		NullToken: "NULL",
+	})
+	if err != nil {
+		return err
+	}
+	if n != 2 {
+		return fmt.Errorf("copied rows mismatch [got=%d/expected=2]", n)
+	}
+	err = db.QueryRow(ctx, `SELECT COUNT(*) FROM go_postgres_test_table WHERE id = 302 AND sm IS NULL`).Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count != 1 {
+		return errors.New("NULL token was not honored")
+	}
+
+	// Malformed record
+	_, err = db.CopyFromCSV(ctx, "go_postgres_test_table", strings.NewReader(
+		"303;abc\n",
+	), postgres.CSVOptions{
+		Columns: []string{"id", "sm"},
+	})
+	if err == nil || !strings.Contains(err.Error(), "line=1") {
+		return fmt.Errorf("expected invalid CSV field error [err=%v]", err)
+	}
+
+	// Cleanup
+	_, err = db.Exec(ctx, `DELETE FROM go_postgres_test_table WHERE id >= 301 AND id <= 399`)
+	if err != nil {
+		return err
+	}
+
+	// Done
+	return nil
+}
+
+func testQueryCSV(ctx context.Context, db *postgres.Database) error {
+	sb := strings.Builder{}
+	err := db.QueryCSV(ctx, &sb, `SELECT * FROM (VALUES (1, 'a,b', NULL::int), (2, 'say "hi"', 3)) AS t (id, txt, num)`)
+	if err != nil {
+		return err
+	}
+	expected := "id,txt,num\n1,\"a,b\",\n2,\"say \"\"hi\"\"\",3\n"
+	if sb.String() != expected {
+		return fmt.Errorf("CSV output mismatch [got=%q/expected=%q]", sb.String(), expected)
+	}
+
+	// Done
+	return nil
+}
+
+func testQueryChan(ctx context.Context, db *postgres.Database) error {
+	ch, err := db.QueryChan(ctx, `SELECT n FROM generate_series(1, 500) AS n`)
+	if err != nil {
+		return err
+	}
+	sum := int32(0)
+	for result := range ch {
+		if result.Err != nil {
+			return result.Err
+		}
+		sum += result.Values["n"].(int32)
+	}
+	if sum != 125250 {
+		return fmt.Errorf("QueryChan sum mismatch [got=%d/expected=125250]", sum)
+	}
+
+	// Cancelling the context must stop the producer and close the channel
+	cancelCtx, cancel := context.WithCancel(ctx)
+	ch, err = db.QueryChan(cancelCtx, `SELECT n FROM generate_series(1, 100000) AS n`)
+	if err != nil {
+		cancel()
+		return err
+	}
+	<-ch
+	cancel()
+	for range ch {
+	}
+
+	// Done
+	return nil
+}
+
+func testServerVersion(ctx context.Context, db *postgres.Database) error {
+	v, err := db.ServerVersion(ctx)
+	if err != nil {
+		return err
+	}
+	if v.Major < 9 || !strings.HasPrefix(v.Full, "PostgreSQL") {
+		return fmt.Errorf("unexpected server version [major=%d/full=%q]", v.Major, v.Full)
+	}
+	if !v.AtLeast(v.Major, 0) || v.AtLeast(v.Major+1, 0) {
+		return errors.New("server version comparison mismatch")
+	}
+
+	// Done
+	return nil
+}
+
+func testTxStats(ctx context.Context, db *postgres.Database) error {
+	err := db.WithinTx(ctx, func(ctx context.Context, tx *postgres.Tx) error {
+		_, err := tx.Exec(ctx, `SELECT 1`)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(ctx, `SELECT * FROM go_postgres_non_existent_table`)
+		return err
+	})
+	if err == nil {
+		return errors.New("transaction did not fail")
+	}
+	var e *postgres.Error
+	if !errors.As(err, &e) || e.TxStats == nil {
+		return fmt.Errorf("transaction statistics not present in error [err=%v]", err)
+	}
+	if e.TxStats.Statements != 2 || e.TxStats.Duration <= 0 {
+		return fmt.Errorf("transaction statistics mismatch [statements=%d/duration=%v]", e.TxStats.Statements, e.TxStats.Duration)
+	}
+
+	// Done
+	return nil
+}
+
+func testLockRow(ctx context.Context, db *postgres.Database) error {
+	return db.WithinTx(ctx, func(ctx context.Context, tx *postgres.Tx) error {
+		var id int
+
+		row, err := db.LockRow(ctx, tx, "go_postgres_test_table", "id", 1, postgres.LockOptions{})
+		if err != nil {
+			return err
+		}
+		err = row.ScanByName(map[string]interface{}{"id": &id})
+		if err != nil {
+			return err
+		}
+		if id != 1 {
+			return fmt.Errorf("locked row mismatch [got=%d/expected=1]", id)
+		}
+
+		// Try to lock the same row from another transaction
+		tx2, err := db.Begin(ctx)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			_ = tx2.Rollback(ctx)
+		}()
+
+		_, err = db.LockRow(ctx, tx2, "go_postgres_test_table", "id", 1, postgres.LockOptions{
+			SkipLocked: true,
+		})
+		if !postgres.IsNoRowsError(err) {
+			return fmt.Errorf("unexpected error locking a row with SkipLocked [err=%v]", err)
+		}
+		_, err = db.LockRow(ctx, tx2, "go_postgres_test_table", "id", 1, postgres.LockOptions{
+			NoWait: true,
+		})
+		if postgres.TypeOfError(err) != postgres.ErrorTypeLockTimeout {
+			return fmt.Errorf("unexpected error locking a row with NoWait [err=%v]", err)
+		}
+
+		// Done
+		return nil
+	})
+}
+
+func testLockRowConcurrent(ctx context.Context, db *postgres.Database) error {
+	wg := sync.WaitGroup{}
+	errs := make(chan error, 8)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(key int) {
+			defer wg.Done()
+
+			errs <- db.WithinTx(ctx, func(ctx context.Context, tx *postgres.Tx) error {
+				var id int
+				var txt *string
+				var ts *time.Time
+
+				row, err := db.LockRow(ctx, tx, "go_postgres_test_table", "id", key, postgres.LockOptions{})
+				if err != nil {
+					return err
+				}
+				err = row.ScanByName(map[string]interface{}{"id": &id, "txt": &txt, "ts": &ts})
+				if err == nil {
+					_, err = row.Values()
+				}
+				if err == nil && id != key {
+					err = fmt.Errorf("locked row mismatch [got=%d/expected=%d]", id, key)
+				}
+				return err
+			})
+		}(i%2 + 1)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	// Done
+	return nil
+}
+
+func testGetByKeysOrdered(ctx context.Context, db *postgres.Database) error {
+	keys := []int{2, 999999, 1, 2}
+	result, err := postgres.GetByKeysOrdered(
+		ctx, db, "go_postgres_test_table", "id", keys,
+		func(row postgres.Row) (int, int, error) {
+			var id int
+
+			err := row.ScanByName(map[string]interface{}{"id": &id})
+			return id, id * 10, err
+		},
+	)
+	if err != nil {
+		return err
+	}
+	if len(result) != 4 || result[1] != nil {
+		return errors.New("unexpected rows returned")
+	}
+	for _, idx := range []int{0, 2, 3} {
+		if result[idx] == nil || *result[idx] != keys[idx]*10 {
+			return fmt.Errorf("row mismatch at index %d", idx)
+		}
+	}
+
+	// Done
+	return nil
+}
+
+func testRowValues(ctx context.Context, db *postgres.Database) error {
+	values, err := db.QueryRow(ctx, `SELECT 1::int, 'abc'::text, '\x0102'::bytea, NULL::int`).Values()
+	if err != nil {
+		return err
+	}
+	if len(values) != 4 || values[0] != int32(1) || values[1] != "abc" || values[3] != nil {
+		return fmt.Errorf("row values mismatch [got=%v]", values)
+	}
+	if b, ok := values[2].([]byte); !ok || len(b) != 2 || b[0] != 1 || b[1] != 2 {
+		return fmt.Errorf("bytea value mismatch [got=%v]", values[2])
+	}
+
+	err = db.QueryRows(ctx, `SELECT n FROM generate_series(1, 3) AS n`).Do(
+		func(ctx context.Context, row postgres.Row) (bool, error) {
+			values, err = row.Values()
+			if err != nil {
+				return false, err
+			}
+			if len(values) != 1 {
+				return false, fmt.Errorf("row values mismatch [got=%v]", values)
+			}
+			return true, nil
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	// Done
+	return nil
+}
+
+func testUndefinedObjectError(ctx context.Context, db *postgres.Database) error {
+	_, err := db.Exec(ctx, `SELECT * FROM go_postgres_non_existent_table`)
+	if !postgres.IsUndefinedObjectError(err) {
+		return fmt.Errorf("unexpected error querying a non-existent table [err=%v]", err)
+	}
+	var e *postgres.Error
+	if !errors.As(err, &e) || e.Details.ObjectName != "go_postgres_non_existent_table" {
+		return fmt.Errorf("undefined object name mismatch [err=%v]", err)
+	}
+
+	_, err = db.Exec(ctx, `SELECT go_postgres_non_existent_column FROM go_postgres_test_table`)
+	if !postgres.IsUndefinedObjectError(err) {
+		return fmt.Errorf("unexpected error querying a non-existent column [err=%v]", err)
+	}
+
+	// Done
+	return nil
+}
+
+func testExplain(ctx context.Context, db *postgres.Database) error {
+	var count int
+
+	result, err := db.Explain(ctx, false, `SELECT * FROM go_postgres_test_table WHERE id = $1`, 1)
+	if err != nil {
+		return err
+	}
+	if len(result.Plan.NodeType) == 0 || result.TotalCost() <= 0 {
+		return fmt.Errorf("unexpected execution plan [plan=%s]", string(result.Raw))
+	}
+
+	// Analyzed data modifying statements must be rolled back
+	err = db.QueryRow(ctx, `SELECT COUNT(*) FROM go_postgres_test_table`).Scan(&count)
+	if err != nil {
+		return err
+	}
+	result, err = db.Explain(ctx, true, `DELETE FROM go_postgres_test_table`)
+	if err != nil {
+		return err
+	}
+	if result.ExecutionTime <= 0 || result.Plan.ActualLoops == 0 {
+		return fmt.Errorf("unexpected analyzed execution plan [plan=%s]", string(result.Raw))
+	}
+	newCount := 0
+	err = db.QueryRow(ctx, `SELECT COUNT(*) FROM go_postgres_test_table`).Scan(&newCount)
+	if err != nil {
+		return err
+	}
+	if newCount != count {
+		return errors.New("analyzed statement was not rolled back")
+	}
+
+	// Done
+	return nil
+}
+
+func testCopyCancellation(ctx context.Context, db *postgres.Database) error {
+	var count int
+
+	_, err := db.Exec(ctx, `CREATE TABLE IF NOT EXISTS go_postgres_copy_test_table (id INT NOT NULL)`)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_, _ = db.Exec(ctx, `DROP TABLE IF EXISTS go_postgres_copy_test_table`)
+	}()
+
+	copyCtx, cancelCopy := context.WithCancel(ctx)
+	defer cancelCopy()
+
+	calls := 0
+	n, err := db.Copy(copyCtx, "go_postgres_copy_test_table", []string{"id"}, func(ctx context.Context, idx int) ([]interface{}, error) {
+		calls += 1
+		if idx == 1000 {
+			cancelCopy()
+		}
+		if idx >= 1000000 {
+			return nil, nil
+		}
+		return []interface{}{idx}, nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		return fmt.Errorf("unexpected error cancelling a copy [err=%v]", err)
+	}
+	if n != 0 || calls != 1001 {
+		return fmt.Errorf("unexpected cancelled copy results [copied=%d/calls=%d]", n, calls)
+	}
+
+	err = db.QueryRow(ctx, `SELECT COUNT(*) FROM go_postgres_copy_test_table`).Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count != 0 {
+		return fmt.Errorf("cancelled copy inserted %d rows", count)
+	}
+
+	// Done
+	return nil
+}
+
+func testConnReset(ctx context.Context, db *postgres.Database) error {
+	return db.WithinConn(ctx, func(ctx context.Context, conn *postgres.Conn) error {
+		var exists bool
+		var appName string
+
+		_, err := conn.Exec(ctx, `SET application_name = 'go_postgres_reset_test'`)
+		if err == nil {
+			_, err = conn.Exec(ctx, `CREATE TEMP TABLE go_postgres_reset_test_table (id INT)`)
+		}
+		if err == nil {
+			// Use a cached prepared statement before resetting
+			err = conn.QueryRow(ctx, `SELECT $1::INT`, 1).Scan(new(int))
+		}
+		if err != nil {
+			return err
+		}
+
+		err = conn.Reset(ctx)
+		if err != nil {
+			return err
+		}
+
+		err = conn.QueryRow(ctx, `SELECT to_regclass('go_postgres_reset_test_table') IS NOT NULL`).Scan(&exists)
+		if err == nil {
+			err = conn.QueryRow(ctx, `SELECT current_setting('application_name')`).Scan(&appName)
+		}
+		if err == nil {
+			err = conn.QueryRow(ctx, `SELECT $1::INT`, 1).Scan(new(int))
+		}
+		if err != nil {
+			return err
+		}
+		if exists || appName == "go_postgres_reset_test" {
+			return errors.New("connection state was not reset")
+		}
+
+		// Done
+		return nil
+	})
+}
+
+func testQueryRowsMapped(ctx context.Context, db *postgres.Database) error {
+	type item struct {
+		n      int
+		square int
+	}
+
+	items, err := postgres.QueryRowsMapped(ctx, db, func(row postgres.Row) (item, error) {
+		var i item
+
+		err := row.Scan(&i.n)
+		i.square = i.n * i.n
+		return i, err
+	}, `SELECT n FROM generate_series(1, 3) AS n ORDER BY n`)
+	if err != nil {
+		return err
+	}
+	if len(items) != 3 || items[2].n != 3 || items[2].square != 9 {
+		return fmt.Errorf("mapped rows mismatch [got=%v]", items)
+	}
+
+	_, err = postgres.QueryRowsMapped(ctx, db, func(row postgres.Row) (item, error) {
+		return item{}, errors.New("mapper failure")
+	}, `SELECT 1`)
+	if err == nil {
+		return errors.New("mapper error was not returned")
+	}
+
+	// Done
+	return nil
+}
+
+func testAsRole(ctx context.Context, db *postgres.Database) error {
+	var sessionUser string
+	var currentUser string
+
+	err := db.QueryRow(ctx, `SELECT session_user`).Scan(&sessionUser)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(ctx, `DROP ROLE IF EXISTS go_postgres_test_role`)
+	if err == nil {
+		_, err = db.Exec(ctx, `CREATE ROLE go_postgres_test_role NOLOGIN`)
+	}
+	if err == nil {
+		_, err = db.Exec(ctx, `GRANT go_postgres_test_role TO `+postgres.QuoteIdentifier(sessionUser))
+	}
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_, _ = db.Exec(ctx, `DROP ROLE IF EXISTS go_postgres_test_role`)
+	}()
+
+	callbackErr := errors.New("callback failure")
+	err = db.AsRole(ctx, "go_postgres_test_role", func(ctx context.Context, conn *postgres.Conn) error {
+		err := conn.QueryRow(ctx, `SELECT current_user`).Scan(&currentUser)
+		if err != nil {
+			return err
+		}
+		return callbackErr
+	})
+	if !errors.Is(err, callbackErr) {
+		return fmt.Errorf("unexpected AsRole error [err=%v]", err)
+	}
+	if currentUser != "go_postgres_test_role" {
+		return fmt.Errorf("role mismatch [got=%v/expected=go_postgres_test_role]", currentUser)
+	}
+
+	// The role must be reset even if the callback failed
+	err = db.WithinConn(ctx, func(ctx context.Context, conn *postgres.Conn) error {
+		return conn.QueryRow(ctx, `SELECT current_user`).Scan(&currentUser)
+	})
+	if err != nil {
+		return err
+	}
+	if currentUser != sessionUser {
+		return fmt.Errorf("role was not reset [got=%v/expected=%v]", currentUser, sessionUser)
+	}
+
+	// Done
+	return nil
+}
+
+func testWithinTxAs(ctx context.Context, db *postgres.Database) error {
+	var sessionUser string
+	var currentUser string
+
+	err := db.QueryRow(ctx, `SELECT session_user`).Scan(&sessionUser)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(ctx, `DROP ROLE IF EXISTS go_postgres_test_tx_role`)
+	if err == nil {
+		_, err = db.Exec(ctx, `CREATE ROLE go_postgres_test_tx_role NOLOGIN`)
+	}
+	if err == nil {
+		_, err = db.Exec(ctx, `GRANT go_postgres_test_tx_role TO `+postgres.QuoteIdentifier(sessionUser))
+	}
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_, _ = db.Exec(ctx, `DROP ROLE IF EXISTS go_postgres_test_tx_role`)
+	}()
+
+	err = db.WithinTxAs(ctx, "go_postgres_test_tx_role", func(ctx context.Context, tx *postgres.Tx) error {
+		return tx.QueryRow(ctx, `SELECT current_user`).Scan(&currentUser)
+	})
+	if err != nil {
+		return err
+	}
+	if currentUser != "go_postgres_test_tx_role" {
+		return fmt.Errorf("role mismatch [got=%v/expected=go_postgres_test_tx_role]", currentUser)
+	}
+
+	// The role only applies to the transaction
+	err = db.WithinTx(ctx, func(ctx context.Context, tx *postgres.Tx) error {
+		return tx.QueryRow(ctx, `SELECT current_user`).Scan(&currentUser)
+	})
+	if err != nil {
+		return err
+	}
+	if currentUser != sessionUser {
+		return fmt.Errorf("role was kept after the transaction [got=%v/expected=%v]", currentUser, sessionUser)
+	}
+
+	// Done
+	return nil
+}
+
+func testBulkInsertIgnore(ctx context.Context, db *postgres.Database) error {
+	_, err := db.Exec(ctx, `CREATE TABLE IF NOT EXISTS go_postgres_ignore_test_table (id INT NOT NULL PRIMARY KEY)`)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_, _ = db.Exec(ctx, `DROP TABLE IF EXISTS go_postgres_ignore_test_table`)
+	}()
+
+	_, err = db.Exec(ctx, `INSERT INTO go_postgres_ignore_test_table (id) VALUES (2), (4)`)
+	if err != nil {
+		return err
+	}
+
+	// Insert ids 1 to 5 where 2 and 4 already exist
+	count, err := db.BulkInsertIgnore(ctx, "go_postgres_ignore_test_table", []string{"id"}, func(ctx context.Context, idx int) ([]interface{}, error) {
+		if idx >= 5 {
+			return nil, nil
+		}
+		return []interface{}{idx + 1}, nil
+	})
+	if err != nil {
+		return err
+	}
+	if count != 3 {
+		return fmt.Errorf("inserted rows count mismatch [got=%v/expected=3]", count)
+	}
+
+	// Done
+	return nil
+}
+
+func testUpdateMap(ctx context.Context, db *postgres.Database) error {
+	var sm int
+	var txt string
+
+	_, err := db.Exec(ctx, `INSERT INTO go_postgres_test_table (id, sm, txt) VALUES (401, 1, 'old')`)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_, _ = db.Exec(ctx, `DELETE FROM go_postgres_test_table WHERE id = 401`)
+	}()
+
+	count, err := db.UpdateMap(ctx, "go_postgres_test_table", map[string]interface{}{
+		"txt": "new",
+		"sm":  2,
+	}, map[string]interface{}{
+		"id": 401,
+		"sm": 1,
+	})
+	if err != nil {
+		return err
+	}
+	if count != 1 {
+		return fmt.Errorf("updated rows count mismatch [got=%v/expected=1]", count)
+	}
+
+	err = db.QueryRow(ctx, `SELECT sm, txt FROM go_postgres_test_table WHERE id = 401`).Scan(&sm, &txt)
+	if err != nil {
+		return err
+	}
+	if sm != 2 || txt != "new" {
+		return fmt.Errorf("updated values mismatch [sm=%v/txt=%v]", sm, txt)
+	}
+
+	// An empty condition set must be rejected
+	_, err = db.UpdateMap(ctx, "go_postgres_test_table", map[string]interface{}{"sm": 3}, nil)
+	if err == nil {
+		return errors.New("update without conditions was not rejected")
+	}
+
+	// Done
+	return nil
+}
+
+func testRunScript(ctx context.Context, db *postgres.Database) error {
+	var count int
+
+	_, err := db.Exec(ctx, `CREATE TABLE IF NOT EXISTS go_postgres_script_test_table (id INT NOT NULL PRIMARY KEY)`)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_, _ = db.Exec(ctx, `DROP TABLE IF EXISTS go_postgres_script_test_table`)
+	}()
+
+	// The second insert fails because of the duplicate key
+	statements := []string{
+		`INSERT INTO go_postgres_script_test_table (id) VALUES (1)`,
+		`INSERT INTO go_postgres_script_test_table (id) VALUES (1)`,
+	}
+
+	err = db.RunScript(ctx, statements, true)
+	if postgres.TypeOfError(err) != postgres.ErrorTypeDuplicateKey {
+		return fmt.Errorf("unexpected atomic script error [err=%v]", err)
+	}
+	err = db.QueryRow(ctx, `SELECT COUNT(*) FROM go_postgres_script_test_table`).Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count != 0 {
+		return fmt.Errorf("atomic script was not rolled back [count=%v]", count)
+	}
+
+	err = db.RunScript(ctx, statements, false)
+	if postgres.TypeOfError(err) != postgres.ErrorTypeDuplicateKey {
+		return fmt.Errorf("unexpected script error [err=%v]", err)
+	}
+	err = db.QueryRow(ctx, `SELECT COUNT(*) FROM go_postgres_script_test_table`).Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count != 1 {
+		return fmt.Errorf("script statements count mismatch [got=%v/expected=1]", count)
+	}
+
+	// Done
+	return nil
+}
+
+func testSetReturningFunctions(ctx context.Context, db *postgres.Database) error {
+	type item struct {
+		id    int
+		label string
+		tags  []string
+	}
+
+	_, err := db.Exec(ctx, `CREATE OR REPLACE FUNCTION go_postgres_test_srf(n INT)
+		RETURNS TABLE (id INT, label TEXT, tags TEXT[]) AS $$
+			SELECT i, 'item-' || i, ARRAY['t' || i, 'x']
+			FROM generate_series(1, n) AS i
+		$$ LANGUAGE SQL`)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_, _ = db.Exec(ctx, `DROP FUNCTION IF EXISTS go_postgres_test_srf(INT)`)
+	}()
+
+	// Columns of TABLE(...) return types are mapped by name
+	items := make([]item, 0)
+	err = db.QueryRows(ctx, `SELECT * FROM go_postgres_test_srf($1) ORDER BY id`, 3).Do(
+		func(ctx context.Context, row postgres.Row) (bool, error) {
+			var i item
+
+			err := row.ScanByName(map[string]interface{}{
+				"tags":  &i.tags,
+				"label": &i.label,
+				"id":    &i.id,
+			})
+			if err != nil {
+				return false, err
+			}
+			items = append(items, i)
+			return true, nil
+		},
+	)
+	if err != nil {
+		return err
+	}
+	if len(items) != 3 || items[2].id != 3 || items[2].label != "item-3" ||
+		!reflect.DeepEqual(items[2].tags, []string{"t3", "x"}) {
+		return fmt.Errorf("set-returning function rows mismatch [got=%v]", items)
+	}
+
+	// Functions returning SETOF a scalar type
+	ids, err := postgres.QueryRowsMapped(ctx, db, func(row postgres.Row) (int, error) {
+		var id int
+
+		err := row.Scan(&id)
+		return id, err
+	}, `SELECT id FROM go_postgres_test_srf($1) ORDER BY id DESC`, 2)
+	if err != nil {
+		return err
+	}
+	if !reflect.DeepEqual(ids, []int{2, 1}) {
+		return fmt.Errorf("set-returning function ids mismatch [got=%v]", ids)
+	}
+
+	// An empty set must not fail
+	err = db.QueryRows(ctx, `SELECT * FROM go_postgres_test_srf($1)`, 0).Do(
+		func(ctx context.Context, row postgres.Row) (bool, error) {
+			return false, errors.New("unexpected row")
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	// Done
+	return nil
+}
+
+func testExecIdempotent(ctx context.Context, db *postgres.Database) error {
+	var count int
+
+	key := fmt.Sprintf("go-postgres-test-idempotent-%d", time.Now().UnixNano())
+	sql := `INSERT INTO go_postgres_test_table (id) VALUES (501)`
+	defer func() {
+		_, _ = db.Exec(ctx, `DELETE FROM go_postgres_test_table WHERE id = 501`)
+	}()
+
+	n, err := db.ExecIdempotent(ctx, key, sql)
+	if err != nil {
+		return err
+	}
+	if n != 1 {
+		return fmt.Errorf("affected rows mismatch [got=%v/expected=1]", n)
+	}
+
+	// The second execution must be skipped
+	_, err = db.ExecIdempotent(ctx, key, sql)
+	if !postgres.IsAlreadyExecutedError(err) {
+		return fmt.Errorf("unexpected idempotent execution error [err=%v]", err)
+	}
+	err = db.QueryRow(ctx, `SELECT COUNT(*) FROM go_postgres_test_table WHERE id = 501`).Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count != 1 {
+		return fmt.Errorf("statement was executed again [count=%v]", count)
+	}
+
+	// Done
+	return nil
+}
+
+func testOnce(ctx context.Context, db *postgres.Database) error {
+	key := fmt.Sprintf("go-postgres-test-once-%d", time.Now().UnixNano())
+
+	// A key used by ExecIdempotent must not affect Once
+	_, err := db.ExecIdempotent(ctx, key, `SELECT 1`)
+	if err != nil {
+		return err
+	}
+
+	calls := 0
+	for i := 0; i < 2; i++ {
+		err = db.Once(ctx, key, func(ctx context.Context, tx *postgres.Tx) error {
+			calls += 1
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if calls != 1 {
+		return fmt.Errorf("once callback calls mismatch [got=%v/expected=1]", calls)
+	}
+
+	// A failed callback can be retried
+	failKey := key + "-fail"
+	err = db.Once(ctx, failKey, func(ctx context.Context, tx *postgres.Tx) error {
+		return errors.New("callback failure")
+	})
+	if err == nil {
+		return errors.New("once callback error was not returned")
+	}
+	calls = 0
+	err = db.Once(ctx, failKey, func(ctx context.Context, tx *postgres.Tx) error {
+		calls += 1
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if calls != 1 {
+		return errors.New("once callback was not retried after a failure")
+	}
+
+	// Done
+	return nil
+}
+
+func testSetMaxConns(ctx context.Context, db *postgres.Database) error {
+	originalMaxConns := db.PoolStats().MaxConns
+
+	err := db.SetMaxConns(0)
+	if err == nil {
+		return errors.New("invalid max connections value was accepted")
+	}
+
+	// Resize while a connection of the old pool is held
+	pinnedCtx, release, err := postgres.WithPinnedConn(ctx, db)
+	if err != nil {
+		return err
+	}
+	err = db.SetMaxConns(1)
+	if err != nil {
+		release()
+		return err
+	}
+	_, err = db.Exec(ctx, `SELECT 1`)
+	if err == nil {
+		// The held connection keeps working
+		_, err = db.Exec(pinnedCtx, `SELECT 1`)
+	}
+	release()
+	if err != nil {
+		return err
+	}
+	if n := db.PoolStats().MaxConns; n != 1 {
+		return fmt.Errorf("max connections mismatch [got=%v/expected=1]", n)
+	}
+
+	// Done
+	return db.SetMaxConns(originalMaxConns)
+}
+
+func testQueryCursor(ctx context.Context, db *postgres.Database) error {
+	// Read more rows than the batch size
+	checkRows := func(rows postgres.Rows) error {
+		expected := 1
+		err := rows.Do(func(ctx context.Context, row postgres.Row) (bool, error) {
+			var n int
+
+			err := row.Scan(&n)
+			if err != nil {
+				return false, err
+			}
+			if n != expected {
+				return false, fmt.Errorf("cursor row mismatch [got=%v/expected=%v]", n, expected)
+			}
+			expected += 1
+			return true, nil
+		})
+		if err == nil && expected != 2501 {
+			err = fmt.Errorf("cursor rows count mismatch [got=%v/expected=2500]", expected-1)
+		}
+		return err
+	}
+
+	err := checkRows(db.QueryCursor(ctx, `SELECT n FROM generate_series(1, $1::int) AS n ORDER BY n`, 2500))
+	if err != nil {
+		return err
+	}
+	return db.WithinTx(ctx, func(ctx context.Context, tx *postgres.Tx) error {
+		return checkRows(tx.QueryCursor(ctx, `SELECT n FROM generate_series(1, $1::int) AS n ORDER BY n`, 2500))
+	})
+}
+
+func testPinnedConn(ctx context.Context, db *postgres.Database) error {
+	var count int
+	var visible bool
+
+	pinnedCtx, release, err := postgres.WithPinnedConn(ctx, db)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	// Temporary tables are only visible within the session that created them
+	_, err = db.Exec(pinnedCtx, `CREATE TEMPORARY TABLE go_postgres_pinned_test_table (id INT NOT NULL)`)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_, _ = db.Exec(pinnedCtx, `DROP TABLE IF EXISTS go_postgres_pinned_test_table`)
+	}()
+
+	err = db.WithinTx(pinnedCtx, func(ctx context.Context, tx *postgres.Tx) error {
+		_, err := tx.Exec(ctx, `INSERT INTO go_postgres_pinned_test_table (id) VALUES (1), (2)`)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	err = db.QueryRow(pinnedCtx, `SELECT COUNT(*) FROM go_postgres_pinned_test_table`).Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count != 2 {
+		return fmt.Errorf("pinned connection rows count mismatch [got=%v/expected=2]", count)
+	}
+
+	// Other connections must not see it
+	err = db.QueryRow(ctx, `SELECT to_regclass('pg_temp.go_postgres_pinned_test_table') IS NOT NULL`).Scan(&visible)
+	if err != nil {
+		return err
+	}
+	if visible {
+		return errors.New("temporary table is visible from another connection")
+	}
+
+	// Done
+	return nil
+}
+
+func testQueryJSONLines(ctx context.Context, db *postgres.Database) error {
+	buf := bytes.Buffer{}
+	err := db.QueryJSONLines(ctx, &buf, `SELECT n AS id, 'item-' || n AS label, NULL::text AS empty
+		FROM generate_series(1, $1::int) AS n ORDER BY n`, 3)
+	if err != nil {
+		return err
+	}
+
+	expected := `{"id":1,"label":"item-1","empty":null}` + "\n" +
+		`{"id":2,"label":"item-2","empty":null}` + "\n" +
+		`{"id":3,"label":"item-3","empty":null}` + "\n"
+	if buf.String() != expected {
+		return fmt.Errorf("JSON lines mismatch [got=%v]", buf.String())
+	}
+
+	// Done
+	return nil
+}
+
+func testCloseWithTimeout(ctx context.Context, db *postgres.Database) error {
+	_, release, err := postgres.WithPinnedConn(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	// Closing must give up while the connection is held
+	closeCtx, cancelClose := context.WithTimeout(ctx, 200*time.Millisecond)
+	err = db.CloseWithTimeout(closeCtx)
+	cancelClose()
+	release()
+	if err == nil {
+		return errors.New("close did not time out while a connection was held")
+	}
+
+	// Done
+	return nil
+}
+
+func testLoadRelated(ctx context.Context, db *postgres.Database) error {
+	result, err := db.LoadRelated(
+		ctx, []int64{1, 2, 4},
+		`SELECT * FROM (VALUES (1::bigint, 'a'), (1, 'b'), (2, 'c'), (3, 'd')) AS v (parent_id, name)`,
+		"parent_id",
+		func(row postgres.Row) (int64, interface{}, error) {
+			var parentId int64
+			var name string
+
+			err := row.Scan(&parentId, &name)
+			return parentId, name, err
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	// Parents without related rows, and rows of other parents, must not be present
+	if len(result) != 2 || len(result[1]) != 2 || len(result[2]) != 1 {
+		return fmt.Errorf("related rows mismatch [got=%v]", result)
+	}
+	names := []string{result[1][0].(string), result[1][1].(string)}
+	sort.Strings(names)
+	if names[0] != "a" || names[1] != "b" || result[2][0].(string) != "c" {
+		return fmt.Errorf("related rows mismatch [got=%v]", result)
+	}
+
+	// Done
+	return nil
+}
+
+func testTxHooks(ctx context.Context, db *postgres.Database) error {
+	committed := 0
+	rolledBack := 0
+
+	// Commit path, including a nested transaction
+	err := db.WithinTx(ctx, func(ctx context.Context, tx *postgres.Tx) error {
+		_ = postgres.OnCommit(ctx, func() { committed += 1 })
+		_ = postgres.OnRollback(ctx, func() { rolledBack += 1 })
+
+		return tx.WithinTx(ctx, func(ctx context.Context, tx *postgres.Tx) error {
+			_ = postgres.OnCommit(ctx, func() { committed += 1 })
+			return nil
+		})
+	})
+	if err != nil {
+		return err
+	}
+	if committed != 2 || rolledBack != 0 {
+		return fmt.Errorf("hooks mismatch [committed=%d/rolledBack=%d]", committed, rolledBack)
+	}
+
+	// Rollback path
+	committed = 0
+	err = db.WithinTx(ctx, func(ctx context.Context, tx *postgres.Tx) error {
+		_ = postgres.OnCommit(ctx, func() { committed += 1 })
+		_ = postgres.OnRollback(ctx, func() { rolledBack += 1 })
+		return errors.New("forced failure")
+	})
+	if err == nil {
+		return errors.New("transaction succeeded but it should fail")
+	}
+	if committed != 0 || rolledBack != 1 {
+		return fmt.Errorf("hooks mismatch [committed=%d/rolledBack=%d]", committed, rolledBack)
+	}
+
+	// Outside a transaction
+	if postgres.OnCommit(ctx, func() {}) == nil {
+		return errors.New("OnCommit succeeded outside a transaction")
+	}
+
+	// Done
+	return nil
+}
+
+func testExplicitTx(ctx context.Context, db *postgres.Database) error {
+	var count int
+	var beforeCommitCalled, commitCalled, rollbackCalled bool
+	var pinnedPid, txPid int
+
+	// Insert a row and roll back
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, `INSERT INTO go_postgres_test_table (id) VALUES (201)`)
+	if err != nil {
+		_ = tx.Rollback(ctx)
+		return err
+	}
+	err = tx.Rollback(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Insert a row and commit
+	tx, err = db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+	txCtx := tx.Context(ctx)
+	err = postgres.BeforeCommit(txCtx, func(ctx context.Context) error {
+		beforeCommitCalled = true
+		return nil
+	})
+	if err == nil {
+		err = postgres.OnCommit(txCtx, func() {
+			commitCalled = true
+		})
+	}
+	if err == nil {
+		err = postgres.OnRollback(txCtx, func() {
+			rollbackCalled = true
+		})
+	}
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(txCtx, `INSERT INTO go_postgres_test_table (id) VALUES (202)`)
+	if err != nil {
+		return err
+	}
+	err = tx.Commit(ctx)
+	if err != nil {
+		return err
+	}
+	if !beforeCommitCalled || !commitCalled || rollbackCalled {
+		return fmt.Errorf("unexpected hooks execution [beforeCommit=%v/commit=%v/rollback=%v]",
+			beforeCommitCalled, commitCalled, rollbackCalled)
+	}
+
+	// Start a transaction on a pinned connection
+	pinnedCtx, release, err := postgres.WithPinnedConn(ctx, db)
+	if err != nil {
+		return err
+	}
+	defer release()
+	err = db.QueryRow(pinnedCtx, `SELECT pg_backend_pid()`).Scan(&pinnedPid)
+	if err != nil {
+		return err
+	}
+	pinnedTx, err := db.Begin(pinnedCtx)
+	if err != nil {
+		return err
+	}
+	err = pinnedTx.QueryRow(ctx, `SELECT pg_backend_pid()`).Scan(&txPid)
+	_ = pinnedTx.Rollback(ctx)
+	if err != nil {
+		return err
+	}
+	if txPid != pinnedPid {
+		return fmt.Errorf("transaction not started on the pinned connection [got=%d/expected=%d]", txPid, pinnedPid)
+	}
+
+	// Verify
+	err = db.QueryRow(ctx, `SELECT COUNT(*) FROM go_postgres_test_table WHERE id IN (201, 202)`).Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count != 1 {
+		return fmt.Errorf("row count mismatch [got=%d/expected=1]", count)
+	}
+	_, err = db.Exec(ctx, `DELETE FROM go_postgres_test_table WHERE id = 202`)
+	if err != nil {
+		return err
+	}
+
+	// Done
+	return nil
+}
+
 func genTestRowDef(index int, write bool) TestRowDef {
 	var r TestRowDef
 
@@ -334,7 +1789,7 @@ func genTestNullableRowDef(index int, write bool) TestNullableRowDef {
 	return r
 }
 
-func insertTestRowDef(ctx context.Context, tx postgres.Tx, rd TestRowDef) error {
+func insertTestRowDef(ctx context.Context, tx *postgres.Tx, rd TestRowDef) error {
 	_, err := tx.Exec(ctx, `
 		INSERT INTO go_postgres_test_table (
 			id, num, sm, bi, bi2, dbl, va, chr, txt, blob, ts, dt, tim, b, js
@@ -416,7 +1871,7 @@ func readMultiTestRowDef(ctx context.Context, db *postgres.Database, compareRd [
 	return rd, nil
 }
 
-func insertTestNullableRowDef(ctx context.Context, tx postgres.Tx, nrd TestNullableRowDef) error {
+func insertTestNullableRowDef(ctx context.Context, tx *postgres.Tx, nrd TestNullableRowDef) error {
 	_, err := tx.Exec(ctx, `
 		INSERT INTO go_postgres_test_table (
 			id, num, sm, bi, bi2, dbl, va, chr, txt, blob, ts, dt, tim, b, js
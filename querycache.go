@@ -0,0 +1,269 @@
+// See the LICENSE file for license details.
+
+package postgres
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// -----------------------------------------------------------------------------
+
+// QueryCache defines a pluggable storage for cached query results.
+type QueryCache interface {
+	// Get returns the value stored with the given key if present.
+	Get(key string) ([]byte, bool)
+
+	// Set stores a value with the given key for the specified duration.
+	Set(key string, value []byte, ttl time.Duration)
+}
+
+type cachedResult struct {
+	Fields []cachedField `json:"fields"`
+	Rows   [][][]byte    `json:"rows"`
+}
+
+type cachedField struct {
+	Name   string `json:"name"`
+	OID    uint32 `json:"oid"`
+	Format int16  `json:"format"`
+}
+
+type cachedRows struct {
+	ctx    context.Context
+	db     *Database
+	result *cachedResult
+	err    error
+}
+
+type cachedRow struct {
+	rows   *cachedRows
+	values [][]byte
+}
+
+// -----------------------------------------------------------------------------
+
+// QueryRowsCached executes a SQL query like QueryRows does but the result is stored in the query
+// cache set in Options for the specified duration. Subsequent calls with the same SQL sentence and
+// arguments return the cached rows without accessing the database.
+//
+// NOTES:
+// ~~~~~
+//  1. The whole result set is kept in memory to be stored in the cache, so use it only with queries
+//     returning a small number of rows.
+//  2. The cache key is derived from the SQL sentence and the JSON representation of the arguments.
+//     If the arguments cannot be encoded as JSON, the cache is bypassed.
+//  3. Cached values are decoded using the standard PGX type mappings. Custom data types are not
+//     supported.
+func (db *Database) QueryRowsCached(ctx context.Context, ttl time.Duration, sql string, args ...interface{}) Rows {
+	if db.queryCache == nil {
+		return db.QueryRows(ctx, sql, args...)
+	}
+	key, err := getQueryCacheKey(sql, args)
+	if err != nil {
+		return db.QueryRows(ctx, sql, args...)
+	}
+	return db.queryRowsCached(ctx, key, ttl, sql, args)
+}
+
+func (db *Database) queryRowsCached(ctx context.Context, key string, ttl time.Duration, sql string, args []interface{}) Rows {
+	// Check if the result is already cached
+	if data, ok := db.queryCache.Get(key); ok {
+		result := cachedResult{}
+		if json.Unmarshal(data, &result) == nil {
+			return &cachedRows{
+				ctx:    ctx,
+				db:     db,
+				result: &result,
+			}
+		}
+	}
+
+	// Run the query and cache the result
+	result, err := db.queryForCache(ctx, sql, args)
+	if err != nil {
+		return &cachedRows{
+			ctx: ctx,
+			db:  db,
+			err: err,
+		}
+	}
+	data, err := json.Marshal(result)
+	if err == nil {
+		db.queryCache.Set(key, data, ttl)
+	}
+
+	// Done
+	return &cachedRows{
+		ctx:    ctx,
+		db:     db,
+		result: result,
+	}
+}
+
+func (db *Database) queryForCache(ctx context.Context, sql string, args []interface{}) (*cachedResult, error) {
+	ctx = db.withAcquireTracking(ctx)
+	rows, err := db.pool.Load().Query(ctx, sql, db.withQueryExecMode(ctx, sql, args)...)
+	if err != nil {
+		return nil, db.newAcquireError(ctx, err, "unable to run query")
+	}
+	return readCachedResult(rows)
+}
+
+// readCachedResult reads all the rows into memory and closes them.
+func readCachedResult(rows pgx.Rows) (*cachedResult, error) {
+	defer rows.Close()
+
+	result := cachedResult{
+		Fields: make([]cachedField, 0),
+		Rows:   make([][][]byte, 0),
+	}
+	for _, fd := range rows.FieldDescriptions() {
+		result.Fields = append(result.Fields, cachedField{
+			Name:   fd.Name,
+			OID:    fd.DataTypeOID,
+			Format: fd.Format,
+		})
+	}
+	for rows.Next() {
+		// Raw values are only valid until the next call to Next so copy them
+		rawValues := rows.RawValues()
+		values := make([][]byte, len(rawValues))
+		for idx, v := range rawValues {
+			if v != nil {
+				values[idx] = append(make([]byte, 0, len(v)), v...)
+			}
+		}
+		result.Rows = append(result.Rows, values)
+	}
+	err := rows.Err()
+	if err != nil {
+		return nil, newError(err, "unable to run query")
+	}
+
+	// Done
+	return &result, nil
+}
+
+func getQueryCacheKey(sql string, args []interface{}) (string, error) {
+	encodedArgs, err := json.Marshal(args)
+	if err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+	_, _ = h.Write([]byte(sql))
+	_, _ = h.Write([]byte{0})
+	_, _ = h.Write(encodedArgs)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// -----------------------------------------------------------------------------
+
+func (r *cachedRows) Do(cb ScanRowsCallback) error {
+	if r.err == nil {
+		for _, values := range r.result.Rows {
+			cont, err := cb(r.ctx, &cachedRow{
+				rows:   r,
+				values: values,
+			})
+			if err != nil {
+				r.err = newError(err, "callback returned failure")
+				break
+			}
+			if !cont {
+				break
+			}
+		}
+	}
+
+	// Done
+	return r.db.handleError(r.ctx, r.err)
+}
+
+func (r *cachedRow) Scan(dest ...interface{}) error {
+	fields := r.rows.result.Fields
+	if len(dest) != len(fields) {
+		err := fmt.Errorf("query returned %d columns but %d scan targets were provided", len(fields), len(dest))
+		return r.rows.db.handleError(r.rows.ctx, newError(err, "unable to scan row"))
+	}
+	return r.scan(dest)
+}
+
+func (r *cachedRow) ScanByName(dest map[string]interface{}) error {
+	fields := r.rows.result.Fields
+	targets := make([]interface{}, len(fields))
+	for name := range dest {
+		found := false
+		for idx, fd := range fields {
+			if fd.Name == name {
+				targets[idx] = dest[name]
+				found = true
+			}
+		}
+		if !found {
+			err := fmt.Errorf("column \"%s\" not found in query result", name)
+			return r.rows.db.handleError(r.rows.ctx, newError(err, "unable to scan row"))
+		}
+	}
+	return r.scan(targets)
+}
+
+func (r *cachedRow) Values() ([]interface{}, error) {
+	// NOTE: Type maps memoize scan plans without locking so each reader needs its own.
+	typeMap := r.rows.db.typeMaps.Get().(*pgtype.Map)
+	defer r.rows.db.typeMaps.Put(typeMap)
+
+	fields := r.rows.result.Fields
+	values := make([]interface{}, len(fields))
+	for idx, fd := range fields {
+		if r.values[idx] == nil {
+			continue
+		}
+		t, ok := typeMap.TypeForOID(fd.OID)
+		if !ok {
+			// Unknown data types are returned as is like PGX does
+			if fd.Format == pgx.TextFormatCode {
+				values[idx] = string(r.values[idx])
+			} else {
+				values[idx] = append(make([]byte, 0, len(r.values[idx])), r.values[idx]...)
+			}
+			continue
+		}
+		v, err := t.Codec.DecodeValue(typeMap, fd.OID, fd.Format, r.values[idx])
+		if err != nil {
+			err = fmt.Errorf("can't decode column #%d: %w", idx+1, err)
+			return nil, r.rows.db.handleError(r.rows.ctx, newError(err, "unable to scan row"))
+		}
+		values[idx] = v
+	}
+	return values, nil
+}
+
+func (r *cachedRow) scan(dest []interface{}) error {
+	var err error
+
+	// NOTE: Type maps memoize scan plans without locking so each reader needs its own.
+	typeMap := r.rows.db.typeMaps.Get().(*pgtype.Map)
+	defer r.rows.db.typeMaps.Put(typeMap)
+
+	fields := r.rows.result.Fields
+	for idx, d := range dest {
+		if d == nil {
+			continue
+		}
+		err = typeMap.Scan(fields[idx].OID, fields[idx].Format, r.values[idx], d)
+		if err != nil {
+			err = fmt.Errorf("can't scan into dest[%d]: %w", idx, err)
+			break
+		}
+	}
+	return r.rows.db.handleError(r.rows.ctx, newError(err, "unable to scan row"))
+}
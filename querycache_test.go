@@ -0,0 +1,193 @@
+// See the LICENSE file for license details.
+
+package postgres_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mxmauro/go-postgres/v2"
+)
+
+// -----------------------------------------------------------------------------
+
+type testQueryCache struct {
+	mutex  sync.Mutex
+	values map[string][]byte
+	hits   int
+	misses int
+}
+
+// -----------------------------------------------------------------------------
+
+func TestQueryRowsCached(t *testing.T) {
+	ctx := context.Background()
+
+	cache := &testQueryCache{
+		values: make(map[string][]byte),
+	}
+	db := openTestDatabaseWithOptions(ctx, t, func(opts *postgres.Options) {
+		opts.QueryCache = cache
+	})
+	defer db.Close()
+
+	query := func() error {
+		count := 0
+		err := db.QueryRowsCached(ctx, time.Minute, `SELECT n, 'item-' || n, now() FROM generate_series(1, $1::int) AS n ORDER BY n`, 50).Do(
+			func(ctx context.Context, row postgres.Row) (bool, error) {
+				var n int
+				var label string
+				var ts time.Time
+
+				err := row.Scan(&n, &label, &ts)
+				if err != nil {
+					return false, err
+				}
+				if label != fmt.Sprintf("item-%d", n) {
+					return false, fmt.Errorf("label mismatch [got=%v]", label)
+				}
+				_, err = row.Values()
+				if err != nil {
+					return false, err
+				}
+				count += 1
+				return true, nil
+			},
+		)
+		if err == nil && count != 50 {
+			err = fmt.Errorf("unexpected rows count [count=%v]", count)
+		}
+		return err
+	}
+
+	// The first call misses and populates the cache, the second one hits it
+	err := query()
+	if err == nil {
+		err = query()
+	}
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+	if cache.misses != 1 || cache.hits != 1 {
+		t.Fatalf("unexpected cache usage [misses=%v/hits=%v]", cache.misses, cache.hits)
+	}
+
+	// Scan cached rows concurrently
+	wg := sync.WaitGroup{}
+	errs := make(chan error, 16)
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- query()
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err = range errs {
+		if err != nil {
+			t.Fatalf("%v", err.Error())
+		}
+	}
+	if cache.misses != 1 {
+		t.Fatalf("unexpected cache misses [misses=%v]", cache.misses)
+	}
+}
+
+func TestQueryRowsCachedInvalidatable(t *testing.T) {
+	ctx := context.Background()
+
+	cache := &testQueryCache{
+		values: make(map[string][]byte),
+	}
+	db := openTestDatabaseWithOptions(ctx, t, func(opts *postgres.Options) {
+		opts.QueryCache = cache
+	})
+	defer db.Close()
+
+	_, err := db.Exec(ctx, `CREATE TABLE IF NOT EXISTS go_postgres_invalidation_test_table (id INT NOT NULL)`)
+	if err == nil {
+		_, err = db.Exec(ctx, `TRUNCATE go_postgres_invalidation_test_table`)
+	}
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+	defer func() {
+		_, _ = db.Exec(ctx, `DROP TABLE IF EXISTS go_postgres_invalidation_test_table`)
+	}()
+
+	count := func() (int, error) {
+		var n int
+
+		err := db.QueryRowsCachedInvalidatable(ctx, "go_postgres_invalidation_test", time.Minute,
+			`SELECT COUNT(*) FROM go_postgres_invalidation_test_table`,
+		).Do(func(ctx context.Context, row postgres.Row) (bool, error) {
+			return false, row.Scan(&n)
+		})
+		return n, err
+	}
+
+	n, err := count()
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+	if n != 0 {
+		t.Fatalf("unexpected rows count [count=%v]", n)
+	}
+
+	// Without a notification, the stale cached result is returned
+	_, err = db.Exec(ctx, `INSERT INTO go_postgres_invalidation_test_table (id) VALUES (1)`)
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+	n, err = count()
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+	if n != 0 {
+		t.Fatalf("cached result was not used [count=%v]", n)
+	}
+
+	// After the notification, the query runs again
+	_, err = db.Exec(ctx, `SELECT pg_notify('go_postgres_invalidation_test', '')`)
+	if err != nil {
+		t.Fatalf("%v", err.Error())
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		n, err = count()
+		if err != nil {
+			t.Fatalf("%v", err.Error())
+		}
+		if n == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("cached result was not invalidated")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func (c *testQueryCache) Get(key string) ([]byte, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	value, ok := c.values[key]
+	if ok {
+		c.hits += 1
+	} else {
+		c.misses += 1
+	}
+	return value, ok
+}
+
+func (c *testQueryCache) Set(key string, value []byte, _ time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.values[key] = value
+}
@@ -0,0 +1,35 @@
+// See the LICENSE file for license details.
+
+package postgres
+
+import (
+	"context"
+)
+
+// -----------------------------------------------------------------------------
+
+// Queryer defines the methods to run queries that are common to Database, Conn and Tx objects.
+type Queryer interface {
+	// QueryRow executes a SQL query that is expected to return a single row.
+	QueryRow(ctx context.Context, sql string, args ...interface{}) Row
+
+	// QueryRows executes a SQL query that can return multiple rows.
+	QueryRows(ctx context.Context, sql string, args ...interface{}) Rows
+}
+
+// ExecerQueryer extends Queryer with the methods to execute commands.
+type ExecerQueryer interface {
+	Queryer
+
+	// Exec executes an SQL statement and returns the number of affected rows.
+	Exec(ctx context.Context, sql string, args ...interface{}) (int64, error)
+
+	// Copy executes a SQL copy query.
+	Copy(ctx context.Context, tableName string, columnNames []string, cb CopyCallback) (int64, error)
+}
+
+// -----------------------------------------------------------------------------
+
+var _ ExecerQueryer = &Database{}
+var _ ExecerQueryer = &Conn{}
+var _ ExecerQueryer = &Tx{}
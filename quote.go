@@ -0,0 +1,31 @@
+// See the LICENSE file for license details.
+
+package postgres
+
+import (
+	"strings"
+)
+
+// -----------------------------------------------------------------------------
+
+// QuoteIdentifier quotes an identifier, like a table or column name, so it can be safely embedded
+// in a SQL sentence.
+//
+// Use it only for identifiers that cannot be passed as query parameters, like dynamic column names
+// in ORDER BY clauses. Values must always be passed using placeholders ($1, $2, ...).
+func QuoteIdentifier(s string) string {
+	return "\"" + strings.ReplaceAll(s, "\"", "\"\"") + "\""
+}
+
+// QuoteLiteral quotes a string literal so it can be safely embedded in a SQL sentence.
+//
+// Use it only for literals that cannot be passed as query parameters, like the values of utility
+// commands such as SET. Values must always be passed using placeholders ($1, $2, ...) when possible.
+func QuoteLiteral(s string) string {
+	s = strings.ReplaceAll(s, "'", "''")
+	if strings.Contains(s, "\\") {
+		// Use the escape string syntax so the result is valid regardless of standard_conforming_strings
+		return "E'" + strings.ReplaceAll(s, "\\", "\\\\") + "'"
+	}
+	return "'" + s + "'"
+}
@@ -0,0 +1,52 @@
+// See the LICENSE file for license details.
+
+package postgres_test
+
+import (
+	"testing"
+
+	"github.com/mxmauro/go-postgres/v2"
+)
+
+// -----------------------------------------------------------------------------
+
+func TestQuoteIdentifier(t *testing.T) {
+	for _, tc := range []struct {
+		value    string
+		expected string
+	}{
+		{`name`, `"name"`},
+		{`Order-Details`, `"Order-Details"`},
+		{`na"me`, `"na""me"`},
+		{`x"; DROP TABLE users; --`, `"x""; DROP TABLE users; --"`},
+	} {
+		if got := postgres.QuoteIdentifier(tc.value); got != tc.expected {
+			t.Fatalf("identifier mismatch [got=%v/expected=%v]", got, tc.expected)
+		}
+	}
+}
+
+func TestPlainTSQuery(t *testing.T) {
+	if s := postgres.PlainTSQuery(2, ""); s != "plainto_tsquery($2::text)" {
+		t.Fatalf("tsquery expression mismatch [got=%v]", s)
+	}
+	if s := postgres.PlainTSQuery(1, "english"); s != "plainto_tsquery('english'::regconfig, $1::text)" {
+		t.Fatalf("tsquery expression mismatch [got=%v]", s)
+	}
+}
+
+func TestQuoteLiteral(t *testing.T) {
+	for _, tc := range []struct {
+		value    string
+		expected string
+	}{
+		{`text`, `'text'`},
+		{`it's`, `'it''s'`},
+		{`back\slash`, `E'back\\slash'`},
+		{`x\'; DROP TABLE users; --`, `E'x\\''; DROP TABLE users; --'`},
+	} {
+		if got := postgres.QuoteLiteral(tc.value); got != tc.expected {
+			t.Fatalf("literal mismatch [got=%v/expected=%v]", got, tc.expected)
+		}
+	}
+}
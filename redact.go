@@ -0,0 +1,131 @@
+// See the LICENSE file for license details.
+
+package postgres
+
+import (
+	"strings"
+)
+
+// -----------------------------------------------------------------------------
+
+// RedactSQL returns a copy of the SQL sentence where string and numeric literals are replaced with
+// `?` so it can be safely logged. Placeholders, identifiers, quoted identifiers and comments are
+// preserved.
+func RedactSQL(sql string) string {
+	sb := strings.Builder{}
+	sb.Grow(len(sql))
+
+	sqlLen := len(sql)
+	for ofs := 0; ofs < sqlLen; {
+		deltaOfs, err := skipSqlComment(sql[ofs:])
+		if err != nil {
+			deltaOfs = sqlLen - ofs
+		}
+		if deltaOfs > 0 {
+			_, _ = sb.WriteString(sql[ofs : ofs+deltaOfs])
+			ofs += deltaOfs
+			continue
+		}
+
+		ch := sql[ofs]
+		switch {
+		case (ch == 'E' || ch == 'e' || ch == 'B' || ch == 'b' || ch == 'X' || ch == 'x') && ofs+1 < sqlLen &&
+			sql[ofs+1] == '\'' && !endsWithIdentifierChar(sql[:ofs]):
+			// Escape or bit string
+			ofs += 1 + skipRedactedString(sql[ofs+1:], ch == 'E' || ch == 'e')
+			_, _ = sb.WriteRune('?')
+
+		case ch == '\'':
+			// Single-quote string
+			ofs += skipRedactedString(sql[ofs:], false)
+			_, _ = sb.WriteRune('?')
+
+		case ch == '"':
+			// Quoted identifier
+			startOfs := ofs
+			ofs += 1
+			for ofs < sqlLen {
+				ofs += 1
+				if sql[ofs-1] == '"' {
+					if ofs >= sqlLen || sql[ofs] != '"' {
+						break
+					}
+					ofs += 1
+				}
+			}
+			_, _ = sb.WriteString(sql[startOfs:ofs])
+
+		case ch == '$' && !endsWithIdentifierChar(sql[:ofs]):
+			startOfs := ofs
+			ofs += 1
+
+			// Placeholder or dollar tag?
+			for ofs < sqlLen && (sql[ofs] == '_' || (sql[ofs] >= '0' && sql[ofs] <= '9') ||
+				(sql[ofs] >= 'A' && sql[ofs] <= 'Z') || (sql[ofs] >= 'a' && sql[ofs] <= 'z')) {
+				ofs += 1
+			}
+			if ofs >= sqlLen || sql[ofs] != '$' || (ofs > startOfs+1 && sql[startOfs+1] >= '0' && sql[startOfs+1] <= '9') {
+				_, _ = sb.WriteString(sql[startOfs:ofs])
+				continue
+			}
+			ofs += 1
+			tag := sql[startOfs:ofs]
+
+			// Find the next tag
+			deltaOfs = strings.Index(sql[ofs:], tag)
+			if deltaOfs < 0 {
+				ofs = sqlLen
+			} else {
+				ofs += deltaOfs + len(tag)
+			}
+			_, _ = sb.WriteRune('?')
+
+		case ((ch >= '0' && ch <= '9') || (ch == '.' && ofs+1 < sqlLen && sql[ofs+1] >= '0' && sql[ofs+1] <= '9')) &&
+			!endsWithIdentifierChar(sql[:ofs]):
+			// Numeric literal
+			ofs += 1
+			for ofs < sqlLen {
+				ch = sql[ofs]
+				if (ch >= '0' && ch <= '9') || ch == '.' || ch == '_' || (ch >= 'A' && ch <= 'Z') || (ch >= 'a' && ch <= 'z') {
+					ofs += 1
+				} else if (ch == '+' || ch == '-') && (sql[ofs-1] == 'e' || sql[ofs-1] == 'E') {
+					ofs += 1
+				} else {
+					break
+				}
+			}
+			_, _ = sb.WriteRune('?')
+
+		default:
+			_ = sb.WriteByte(ch)
+			ofs += 1
+		}
+	}
+
+	// Done
+	return sb.String()
+}
+
+// -----------------------------------------------------------------------------
+
+// skipRedactedString returns the length of the quoted string at the start of s. If the string is
+// not closed, the length of s is returned.
+func skipRedactedString(s string, backslashEscapes bool) int {
+	sLen := len(s)
+	ofs := 1
+	for ofs < sLen {
+		if backslashEscapes && s[ofs] == '\\' {
+			ofs += 2
+			continue
+		}
+		ofs += 1
+		if s[ofs-1] == '\'' {
+			if ofs >= sLen || s[ofs] != '\'' {
+				return ofs // End of string
+			}
+			// Double single-quotes
+			ofs += 1
+		}
+	}
+	return sLen
+}
@@ -0,0 +1,28 @@
+// See the LICENSE file for license details.
+
+package postgres_test
+
+import (
+	"testing"
+
+	"github.com/mxmauro/go-postgres/v2"
+)
+
+// -----------------------------------------------------------------------------
+
+func TestRedactSQL(t *testing.T) {
+	for _, tc := range []struct {
+		value    string
+		expected string
+	}{
+		{`SELECT * FROM users WHERE id = 10`, `SELECT * FROM users WHERE id = ?`},
+		{`SELECT * FROM users WHERE name = 'John''s' AND age > 2.5e+3`, `SELECT * FROM users WHERE name = ? AND age > ?`},
+		{`SELECT E'it\'s', X'FF', $1, table1.col2 FROM "tbl 1"`, `SELECT ?, ?, $1, table1.col2 FROM "tbl 1"`},
+		{`SELECT $$secret$$, $tag$ 'x' $tag$ -- 'comment'`, `SELECT ?, ? -- 'comment'`},
+		{`SELECT 'open`, `SELECT ?`},
+	} {
+		if got := postgres.RedactSQL(tc.value); got != tc.expected {
+			t.Fatalf("redacted sql mismatch [got=%v/expected=%v]", got, tc.expected)
+		}
+	}
+}
@@ -3,6 +3,8 @@
 package postgres
 
 import (
+	"context"
+
 	"github.com/jackc/pgx/v5"
 )
 
@@ -12,16 +14,79 @@ import (
 type Row interface {
 	// Scan saves the content of the current row in the destination variables.
 	Scan(dest ...interface{}) error
+
+	// ScanByName saves the content of the columns whose names match the keys of the provided map in
+	// the associated destination variables. Other columns are ignored.
+	ScanByName(dest map[string]interface{}) error
+
+	// Values returns the content of all the columns of the current row decoded using the default
+	// PGX type mappings, for e.g. byte arrays are returned as []byte and timestamps as time.Time.
+	Values() ([]interface{}, error)
 }
 
 type rowGetter struct {
-	db  *Database
-	row pgx.Row
+	ctx  context.Context
+	db   *Database
+	rows pgx.Rows
+	err  error
 }
 
 // -----------------------------------------------------------------------------
 
 func (r *rowGetter) Scan(dest ...interface{}) error {
-	err := r.row.Scan(dest...)
-	return r.db.handleError(newError(err, "unable to scan row"))
+	return r.scan(func(rows pgx.Rows) error {
+		err := checkScanTargets(rows.FieldDescriptions(), dest)
+		if err == nil {
+			err = rows.Scan(dest...)
+		}
+		return err
+	})
+}
+
+func (r *rowGetter) ScanByName(dest map[string]interface{}) error {
+	return r.scan(func(rows pgx.Rows) error {
+		targets, err := getScanTargetsByName(rows.FieldDescriptions(), dest)
+		if err == nil {
+			err = rows.Scan(targets...)
+		}
+		return err
+	})
+}
+
+func (r *rowGetter) Values() ([]interface{}, error) {
+	var values []interface{}
+
+	err := r.scan(func(rows pgx.Rows) error {
+		var err error
+
+		values, err = rows.Values()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+func (r *rowGetter) scan(read func(rows pgx.Rows) error) error {
+	if r.err != nil {
+		return r.db.handleError(r.ctx, r.err)
+	}
+	err := r.rows.Err()
+	if err == nil {
+		if r.rows.Next() {
+			err = read(r.rows)
+		} else {
+			err = r.rows.Err()
+			if err == nil {
+				err = pgx.ErrNoRows
+			}
+		}
+	}
+	r.rows.Close()
+	if err == nil {
+		// Catch errors reported by the server after the row was received
+		err = r.rows.Err()
+	}
+	return r.db.handleError(r.ctx, newError(err, "unable to scan row"))
 }
@@ -45,10 +45,29 @@ func (r *rowsGetter) Do(cb ScanRowsCallback) error {
 	}
 
 	// Done
-	return r.db.handleError(r.err)
+	return r.db.handleError(r.ctx, r.err)
 }
 
 func (r *rowsGetter) Scan(dest ...interface{}) error {
-	err := r.rows.Scan(dest...)
-	return r.db.handleError(newError(err, "unable to scan row"))
+	err := checkScanTargets(r.rows.FieldDescriptions(), dest)
+	if err == nil {
+		err = r.rows.Scan(dest...)
+	}
+	return r.db.handleError(r.ctx, newError(err, "unable to scan row"))
+}
+
+func (r *rowsGetter) Values() ([]interface{}, error) {
+	values, err := r.rows.Values()
+	if err != nil {
+		return nil, r.db.handleError(r.ctx, newError(err, "unable to scan row"))
+	}
+	return values, nil
+}
+
+func (r *rowsGetter) ScanByName(dest map[string]interface{}) error {
+	targets, err := getScanTargetsByName(r.rows.FieldDescriptions(), dest)
+	if err == nil {
+		err = r.rows.Scan(targets...)
+	}
+	return r.db.handleError(r.ctx, newError(err, "unable to scan row"))
 }
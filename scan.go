@@ -0,0 +1,57 @@
+// See the LICENSE file for license details.
+
+package postgres
+
+import (
+	"reflect"
+)
+
+// -----------------------------------------------------------------------------
+
+// ScanLenient saves the content of the row in the destination variables like Row.Scan does, but
+// NULL values read into non-pointer destinations are converted to the zero value of the destination
+// type instead of returning an error.
+//
+// This behavior is opt-in. Use Row.Scan if NULL values must be detected.
+func ScanLenient(row Row, dest ...interface{}) error {
+	wrapped := make([]bool, len(dest))
+	tmp := make([]interface{}, len(dest))
+	for idx, d := range dest {
+		tmp[idx] = d
+
+		// Only wrap non-nil pointers to non-pointer values
+		if d == nil {
+			continue
+		}
+		v := reflect.ValueOf(d)
+		if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() == reflect.Pointer {
+			continue
+		}
+
+		// Scan into a pointer to pointer so the underlying library can store NULLs
+		tmp[idx] = reflect.New(v.Type()).Interface()
+		wrapped[idx] = true
+	}
+
+	err := row.Scan(tmp...)
+	if err != nil {
+		return err
+	}
+
+	// Copy values to the real destinations
+	for idx, d := range dest {
+		if !wrapped[idx] {
+			continue
+		}
+		src := reflect.ValueOf(tmp[idx]).Elem()
+		target := reflect.ValueOf(d).Elem()
+		if src.IsNil() {
+			target.Set(reflect.Zero(target.Type()))
+		} else {
+			target.Set(src.Elem())
+		}
+	}
+
+	// Done
+	return nil
+}
@@ -0,0 +1,42 @@
+// See the LICENSE file for license details.
+
+package postgres
+
+import (
+	"context"
+)
+
+// -----------------------------------------------------------------------------
+
+// RunScript executes the given statements, in order, on a single connection and stops at the first
+// one that fails.
+//
+// If atomic is false, each statement runs in its own implicit transaction, like WithinConn does, so
+// the ones executed before the failure remain applied. If atomic is true, all of them run within a
+// single transaction that is committed only if all succeed.
+//
+// Statements that cannot run inside a transaction block, like CREATE INDEX CONCURRENTLY or VACUUM,
+// require atomic to be false.
+func (db *Database) RunScript(ctx context.Context, statements []string, atomic bool) error {
+	if atomic {
+		return db.WithinTx(ctx, func(ctx context.Context, tx *Tx) error {
+			for _, sql := range statements {
+				_, err := tx.Exec(ctx, sql)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+
+	return db.WithinConn(ctx, func(ctx context.Context, conn *Conn) error {
+		for _, sql := range statements {
+			_, err := conn.Exec(ctx, sql)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
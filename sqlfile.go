@@ -0,0 +1,186 @@
+// See the LICENSE file for license details.
+
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+)
+
+// -----------------------------------------------------------------------------
+
+// QueryFromFS reads a SQL file from the given file system, for e.g. one embedded with
+// `//go:embed`, and returns its content without the leading byte order mark and the surrounding
+// whitespace.
+func QueryFromFS(fsys fs.FS, name string) (string, error) {
+	content, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return "", err
+	}
+	sql := strings.TrimPrefix(string(content), "\uFEFF")
+	return strings.TrimSpace(sql), nil
+}
+
+// ExecFile reads a SQL file from the given file system using QueryFromFS and executes it. If no
+// arguments are provided, the file can contain several sentences separated by semicolons.
+func (db *Database) ExecFile(ctx context.Context, fsys fs.FS, name string, args ...interface{}) (int64, error) {
+	sql, err := QueryFromFS(fsys, name)
+	if err != nil {
+		return 0, err
+	}
+	return db.Exec(ctx, sql, args...)
+}
+
+// -----------------------------------------------------------------------------
+
+const (
+	namedQueryMarker = "-- name:"
+)
+
+// -----------------------------------------------------------------------------
+
+// QuerySet is a registry of named SQL queries loaded with LoadQueries.
+type QuerySet struct {
+	queries map[string]string
+}
+
+// -----------------------------------------------------------------------------
+
+// LoadQueries reads the SQL files matching the given pattern from the file system and returns the
+// set of named queries they contain.
+//
+// The expected format is the following:
+// -- name: QueryName
+// The SQL sentence, that can span several lines
+// (extra name/sql sentence pairs)
+//
+// Names must be unique across all the files. Comments and blank lines before the first name are
+// ignored.
+func LoadQueries(fsys fs.FS, pattern string) (*QuerySet, error) {
+	filenames, err := fs.Glob(fsys, pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	qs := QuerySet{
+		queries: make(map[string]string),
+	}
+	for _, filename := range filenames {
+		var content string
+
+		content, err = QueryFromFS(fsys, filename)
+		if err != nil {
+			return nil, err
+		}
+		err = qs.parse(content)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse queries file \"%s\" [err=%w]", filename, err)
+		}
+	}
+
+	// Done
+	return &qs, nil
+}
+
+// Get returns the SQL sentence of the query with the given name.
+func (qs *QuerySet) Get(name string) (string, bool) {
+	sql, ok := qs.queries[name]
+	return sql, ok
+}
+
+// Names returns the sorted names of the queries in the set.
+func (qs *QuerySet) Names() []string {
+	names := make([]string, 0, len(qs.queries))
+	for name := range qs.queries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (qs *QuerySet) parse(content string) error {
+	currentName := ""
+	currentSql := strings.Builder{}
+
+	addQuery := func() error {
+		if len(currentName) == 0 {
+			return nil
+		}
+		sql := strings.TrimSpace(currentSql.String())
+		if len(sql) == 0 {
+			return fmt.Errorf("query \"%s\" is empty", currentName)
+		}
+		if _, ok := qs.queries[currentName]; ok {
+			return fmt.Errorf("duplicate query name \"%s\"", currentName)
+		}
+		qs.queries[currentName] = sql
+		return nil
+	}
+
+	contentLen := len(content)
+	for ofs := 0; ofs < contentLen; {
+		eolOfs := ofs + findEol(content[ofs:])
+		line := content[ofs:eolOfs]
+		ofs = eolOfs + skipEol(content[eolOfs:])
+
+		// Is it a name marker?
+		trimmedLine := line[skipSpaces(line):]
+		if strings.HasPrefix(trimmedLine, namedQueryMarker) {
+			err := addQuery()
+			if err != nil {
+				return err
+			}
+
+			currentName = strings.Trim(trimmedLine[len(namedQueryMarker):], " \t-=#")
+			if len(currentName) == 0 {
+				return errors.New("empty query name")
+			}
+			currentSql.Reset()
+			continue
+		}
+
+		if len(currentName) == 0 {
+			// Outside a named block, only comments and blank lines are allowed
+			if len(trimmedLine) > 0 && !strings.HasPrefix(trimmedLine, "--") {
+				return errors.New("SQL sentence found outside a named query")
+			}
+			continue
+		}
+
+		_, _ = currentSql.WriteString(line)
+		_, _ = currentSql.WriteRune('\n')
+	}
+
+	// Done
+	return addQuery()
+}
+
+// QueryRowNamed executes the named query from the given set and returns a single row.
+func (db *Database) QueryRowNamed(ctx context.Context, qs *QuerySet, name string, args ...interface{}) Row {
+	sql, ok := qs.Get(name)
+	if !ok {
+		return &rowGetter{
+			ctx: ctx,
+			db:  db,
+			err: fmt.Errorf("query \"%s\" not found", name),
+		}
+	}
+	return db.QueryRow(ctx, sql, args...)
+}
+
+// QueryRowsNamed executes the named query from the given set and returns the rows.
+func (db *Database) QueryRowsNamed(ctx context.Context, qs *QuerySet, name string, args ...interface{}) Rows {
+	sql, ok := qs.Get(name)
+	if !ok {
+		return &rowsGetter{
+			db:  db,
+			ctx: ctx,
+			err: fmt.Errorf("query \"%s\" not found", name),
+		}
+	}
+	return db.QueryRows(ctx, sql, args...)
+}
@@ -0,0 +1,70 @@
+// See the LICENSE file for license details.
+
+package postgres_test
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/mxmauro/go-postgres/v2"
+)
+
+// -----------------------------------------------------------------------------
+
+func TestQueryFromFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"queries/get_user.sql": &fstest.MapFile{
+			Data: []byte("\uFEFF\n  SELECT * FROM users WHERE id = $1;\n\n"),
+		},
+	}
+
+	sql, err := postgres.QueryFromFS(fsys, "queries/get_user.sql")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if sql != "SELECT * FROM users WHERE id = $1;" {
+		t.Fatalf("query mismatch [got=%q]", sql)
+	}
+
+	_, err = postgres.QueryFromFS(fsys, "queries/missing.sql")
+	if err == nil {
+		t.Fatal("missing file did not fail")
+	}
+}
+
+func TestLoadQueries(t *testing.T) {
+	fsys := fstest.MapFS{
+		"queries/users.sql": &fstest.MapFile{
+			Data: []byte("-- Users queries\n\n-- name: GetUser\nSELECT *\nFROM users\nWHERE id = $1;\n\n" +
+				"-- name: DeleteUser\nDELETE FROM users WHERE id = $1;\n"),
+		},
+		"queries/orders.sql": &fstest.MapFile{
+			Data: []byte("-- name: GetOrders\nSELECT * FROM orders;"),
+		},
+	}
+
+	qs, err := postgres.LoadQueries(fsys, "queries/*.sql")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	names := qs.Names()
+	if len(names) != 3 || names[0] != "DeleteUser" || names[1] != "GetOrders" || names[2] != "GetUser" {
+		t.Fatalf("query names mismatch [got=%v]", names)
+	}
+	sql, ok := qs.Get("GetUser")
+	if !ok || sql != "SELECT *\nFROM users\nWHERE id = $1;" {
+		t.Fatalf("query mismatch [got=%q]", sql)
+	}
+
+	for _, data := range []string{
+		"SELECT 1;\n-- name: A\nSELECT 2;",
+		"-- name: A\nSELECT 1;\n-- name: A\nSELECT 2;",
+		"-- name: A\n-- name: B\nSELECT 1;",
+		"-- name:\nSELECT 1;",
+	} {
+		_, err = postgres.LoadQueries(fstest.MapFS{"q.sql": &fstest.MapFile{Data: []byte(data)}}, "*.sql")
+		if err == nil {
+			t.Fatalf("invalid content did not fail [content=%q]", data)
+		}
+	}
+}
@@ -0,0 +1,224 @@
+// See the LICENSE file for license details.
+
+package postgres
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// -----------------------------------------------------------------------------
+
+type structColumn struct {
+	name  string
+	index []int
+}
+
+// -----------------------------------------------------------------------------
+
+// CopyStructs copies a slice of structs (or pointers to structs) into the specified table using
+// a COPY command.
+//
+// Column names are taken from the `db` tag of each exported field. Fields without the tag use
+// the lowercase field name and fields tagged with `db:"-"` are ignored. Embedded structs are
+// flattened.
+func (db *Database) CopyStructs(ctx context.Context, tableName string, values interface{}) (int64, error) {
+	v, columns, err := getStructSlice(values)
+	if err != nil {
+		return 0, err
+	}
+	if v.Len() == 0 {
+		return 0, nil
+	}
+
+	columnNames := make([]string, len(columns))
+	for idx, col := range columns {
+		columnNames[idx] = col.name
+	}
+
+	return db.Copy(ctx, tableName, columnNames, func(ctx context.Context, idx int) ([]interface{}, error) {
+		if idx >= v.Len() {
+			return nil, nil
+		}
+		return getStructValues(v.Index(idx), columns)
+	})
+}
+
+// InsertUnnest inserts a slice of structs (or pointers to structs) into the specified table using a
+// single `INSERT ... SELECT * FROM unnest(...)` sentence. The values of each column are sent as
+// one array parameter so the number of parameters does not depend on the number of rows.
+//
+// If no columns are specified, all the struct columns are inserted. Struct columns are resolved
+// like CopyStructs does and the arrays are annotated with the table column types. Array columns
+// are not supported because unnest flattens multi-dimensional arrays.
+func (db *Database) InsertUnnest(ctx context.Context, tableName string, columns []string, values interface{}) (int64, error) {
+	v, structColumns, err := getStructSlice(values)
+	if err != nil {
+		return 0, err
+	}
+	if v.Len() == 0 {
+		return 0, nil
+	}
+
+	// Pick the columns to insert
+	if len(columns) > 0 {
+		selectedColumns := make([]structColumn, 0, len(columns))
+		for _, name := range columns {
+			found := false
+			for _, col := range structColumns {
+				if col.name == name {
+					selectedColumns = append(selectedColumns, col)
+					found = true
+					break
+				}
+			}
+			if !found {
+				return 0, errors.New("column \"" + name + "\" not found in struct")
+			}
+		}
+		structColumns = selectedColumns
+	}
+
+	// Get the table column types
+	columnTypes := make(map[string]string)
+	err = db.QueryRows(
+		ctx,
+		`SELECT attname, format_type(atttypid, atttypmod) FROM pg_attribute WHERE attrelid = $1::regclass AND
+		attnum > 0 AND NOT attisdropped`,
+		db.quoteTableName(tableName),
+	).Do(func(ctx context.Context, row Row) (bool, error) {
+		var name string
+		var typeName string
+
+		err := row.Scan(&name, &typeName)
+		if err == nil {
+			columnTypes[name] = typeName
+		}
+		return true, err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	// Build one array per column
+	elemType := v.Type().Elem()
+	if elemType.Kind() == reflect.Pointer {
+		elemType = elemType.Elem()
+	}
+	arrays := make([]reflect.Value, len(structColumns))
+	for idx, col := range structColumns {
+		arrays[idx] = reflect.MakeSlice(reflect.SliceOf(elemType.FieldByIndex(col.index).Type), v.Len(), v.Len())
+	}
+	for rowIdx := 0; rowIdx < v.Len(); rowIdx++ {
+		item := v.Index(rowIdx)
+		if item.Kind() == reflect.Pointer {
+			if item.IsNil() {
+				return 0, errors.New("nil struct pointer found")
+			}
+			item = item.Elem()
+		}
+		for idx, col := range structColumns {
+			arrays[idx].Index(rowIdx).Set(item.FieldByIndex(col.index))
+		}
+	}
+
+	// Build the sentence
+	sbColumns := strings.Builder{}
+	sbUnnest := strings.Builder{}
+	args := make([]interface{}, len(structColumns))
+	for idx, col := range structColumns {
+		typeName, ok := columnTypes[col.name]
+		if !ok {
+			return 0, errors.New("column \"" + col.name + "\" not found in table")
+		}
+		if idx > 0 {
+			_, _ = sbColumns.WriteString(", ")
+			_, _ = sbUnnest.WriteString(", ")
+		}
+		_, _ = sbColumns.WriteString(QuoteIdentifier(col.name))
+		_, _ = sbUnnest.WriteString("$" + strconv.Itoa(idx+1) + "::" + typeName + "[]")
+		args[idx] = arrays[idx].Interface()
+	}
+
+	return db.Exec(
+		ctx,
+		"INSERT INTO "+db.quoteTableName(tableName)+" ("+sbColumns.String()+") SELECT * FROM unnest("+sbUnnest.String()+")",
+		args...,
+	)
+}
+
+// -----------------------------------------------------------------------------
+
+func getStructSlice(values interface{}) (reflect.Value, []structColumn, error) {
+	v := reflect.ValueOf(values)
+	if v.Kind() == reflect.Pointer {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return reflect.Value{}, nil, errors.New("values must be a slice of structs")
+	}
+
+	t := v.Type().Elem()
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return reflect.Value{}, nil, errors.New("values must be a slice of structs")
+	}
+
+	columns := getStructColumns(t, nil)
+	if len(columns) == 0 {
+		return reflect.Value{}, nil, errors.New("struct has no columns")
+	}
+
+	// Done
+	return v, columns, nil
+}
+
+func getStructColumns(t reflect.Type, parentIndex []int) []structColumn {
+	columns := make([]structColumn, 0)
+	for idx := 0; idx < t.NumField(); idx++ {
+		field := t.Field(idx)
+
+		index := append(append(make([]int, 0, len(parentIndex)+1), parentIndex...), idx)
+
+		tag, hasTag := field.Tag.Lookup("db")
+		if tag == "-" {
+			continue
+		}
+		if field.Anonymous && !hasTag && field.Type.Kind() == reflect.Struct {
+			columns = append(columns, getStructColumns(field.Type, index)...)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+
+		name := strings.Split(tag, ",")[0]
+		if len(name) == 0 {
+			name = strings.ToLower(field.Name)
+		}
+		columns = append(columns, structColumn{
+			name:  name,
+			index: index,
+		})
+	}
+	return columns
+}
+
+func getStructValues(v reflect.Value, columns []structColumn) ([]interface{}, error) {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return nil, errors.New("nil struct pointer found")
+		}
+		v = v.Elem()
+	}
+	values := make([]interface{}, len(columns))
+	for idx, col := range columns {
+		values[idx] = v.FieldByIndex(col.index).Interface()
+	}
+	return values, nil
+}
@@ -0,0 +1,22 @@
+// See the LICENSE file for license details.
+
+package postgres
+
+import (
+	"strconv"
+)
+
+// -----------------------------------------------------------------------------
+
+// PlainTSQuery returns a SQL expression that converts the search text passed in the query parameter
+// at the given position (starting from 1) into a tsquery using the server's plainto_tsquery
+// function. Any punctuation in the text is ignored, so the user input can be passed as is. If
+// config is not empty, it specifies the text search configuration, for e.g. "english". E.g.:
+//
+//	db.QueryRows(ctx, `SELECT id FROM docs WHERE body_tsv @@ `+postgres.PlainTSQuery(1, "english"), userInput)
+func PlainTSQuery(paramIdx int, config string) string {
+	if len(config) == 0 {
+		return "plainto_tsquery($" + strconv.Itoa(paramIdx) + "::text)"
+	}
+	return "plainto_tsquery(" + QuoteLiteral(config) + "::regconfig, $" + strconv.Itoa(paramIdx) + "::text)"
+}
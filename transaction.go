@@ -4,6 +4,7 @@ package postgres
 
 import (
 	"context"
+	"errors"
 
 	"github.com/jackc/pgx/v5"
 )
@@ -12,8 +13,11 @@ import (
 
 // Tx encloses a transaction object.
 type Tx struct {
-	db *Database
-	tx pgx.Tx
+	db       *Database
+	tx       pgx.Tx
+	hooks    *txHooks
+	stats    *txStats
+	explicit bool
 }
 
 // -----------------------------------------------------------------------------
@@ -23,29 +27,79 @@ func (tx *Tx) DB() *Database {
 	return tx.db
 }
 
+// Context returns a copy of the given context bound to the transaction so BeforeCommit, OnCommit
+// and OnRollback can be used with transactions started with Database.Begin. Transactions managed
+// by WithinTx already provide such a context to the callback.
+func (tx *Tx) Context(ctx context.Context) context.Context {
+	return tx.stats.attach(tx.hooks.attach(ctx))
+}
+
 // Exec executes an SQL statement within the transaction.
 func (tx *Tx) Exec(ctx context.Context, sql string, args ...interface{}) (int64, error) {
+	ctx = tx.stats.track(ctx)
+	err := tx.db.validateExecSql(ctx, tx.tx.Conn().PgConn(), sql, args)
+	if err != nil {
+		return 0, tx.db.handleError(ctx, err)
+	}
 	affectedRows := int64(0)
-	ct, err := tx.tx.Exec(ctx, sql, args...)
+	ct, err := tx.tx.Exec(ctx, sql, tx.db.withQueryExecMode(ctx, sql, args)...)
 	if err == nil {
 		affectedRows = ct.RowsAffected()
 	} else {
 		err = newError(err, "unable to execute command")
 	}
-	return affectedRows, tx.db.handleError(err)
+	return affectedRows, tx.db.handleError(ctx, err)
+}
+
+// ExecTag executes an SQL statement within the transaction and returns the command tag details.
+func (tx *Tx) ExecTag(ctx context.Context, sql string, args ...interface{}) (CommandTag, error) {
+	ctx = tx.stats.track(ctx)
+	err := tx.db.validateExecSql(ctx, tx.tx.Conn().PgConn(), sql, args)
+	if err != nil {
+		return CommandTag{}, tx.db.handleError(ctx, err)
+	}
+	tag := CommandTag{}
+	ct, err := tx.tx.Exec(ctx, sql, tx.db.withQueryExecMode(ctx, sql, args)...)
+	if err == nil {
+		tag = newCommandTag(ct)
+	} else {
+		err = newError(err, "unable to execute command")
+	}
+	return tag, tx.db.handleError(ctx, err)
 }
 
 // QueryRow executes a SQL query within the transaction.
 func (tx *Tx) QueryRow(ctx context.Context, sql string, args ...interface{}) Row {
+	ctx = tx.stats.track(ctx)
+	err := tx.db.validateSql(ctx, tx.tx.Conn().PgConn(), sql, args)
+	if err != nil {
+		return &rowGetter{
+			ctx: ctx,
+			db:  tx.db,
+			err: err,
+		}
+	}
+	rows, err := tx.tx.Query(ctx, sql, tx.db.withQueryExecMode(ctx, sql, args)...)
 	return &rowGetter{
-		db:  tx.db,
-		row: tx.tx.QueryRow(ctx, sql, args...),
+		ctx:  ctx,
+		db:   tx.db,
+		rows: rows,
+		err:  newError(err, "unable to scan row"),
 	}
 }
 
 // QueryRows executes a SQL query within the transaction.
 func (tx *Tx) QueryRows(ctx context.Context, sql string, args ...interface{}) Rows {
-	rows, err := tx.tx.Query(ctx, sql, args...)
+	ctx = tx.stats.track(ctx)
+	err := tx.db.validateSql(ctx, tx.tx.Conn().PgConn(), sql, args)
+	if err != nil {
+		return &rowsGetter{
+			db:  tx.db,
+			ctx: ctx,
+			err: err,
+		}
+	}
+	rows, err := tx.tx.Query(ctx, sql, tx.db.withQueryExecMode(ctx, sql, args)...)
 	return &rowsGetter{
 		db:   tx.db,
 		ctx:  ctx,
@@ -54,11 +108,12 @@ func (tx *Tx) QueryRows(ctx context.Context, sql string, args ...interface{}) Ro
 	}
 }
 
-// Copy executes a SQL copy query within the transaction.
+// Copy executes a SQL copy query within the transaction. See Database.Copy.
 func (tx *Tx) Copy(ctx context.Context, tableName string, columnNames []string, cb CopyCallback) (int64, error) {
+	ctx = tx.stats.track(ctx)
 	n, err := tx.tx.CopyFrom(
 		ctx,
-		pgx.Identifier{tableName},
+		tx.db.getTableIdentifier(tableName),
 		columnNames,
 		&copyWithCallback{
 			ctx: ctx,
@@ -67,21 +122,31 @@ func (tx *Tx) Copy(ctx context.Context, tableName string, columnNames []string,
 	)
 
 	// Done
-	return n, tx.db.handleError(newError(err, "unable to execute command"))
+	return n, tx.db.handleError(ctx, newError(err, "unable to execute command"))
 }
 
 // WithinTx executes a callback function within the context of a nested transaction.
 func (tx *Tx) WithinTx(ctx context.Context, cb WithinTxCallback) error {
+	ctx = tx.stats.attach(ctx)
 	innerTx, err := tx.tx.Begin(ctx)
 	if err == nil {
-		err = cb(ctx, Tx{
-			db: tx.db,
-			tx: innerTx,
+		hooks := newTxHooks(tx.hooks)
+		ctx = hooks.attach(ctx)
+		err = cb(ctx, &Tx{
+			db:    tx.db,
+			tx:    innerTx,
+			hooks: hooks,
+			stats: tx.stats,
 		})
 		if err == nil {
-			err = innerTx.Commit(ctx)
-			if err != nil {
-				err = newError(err, "unable to commit db transaction")
+			err = hooks.runBeforeCommit(ctx)
+			if err == nil {
+				err = innerTx.Commit(ctx)
+				if err != nil {
+					err = newError(err, "unable to commit db transaction")
+				}
+			} else {
+				err = newError(err, "before commit callback returned failure")
 			}
 		} else {
 			err = newError(err, "callback returned failure")
@@ -89,8 +154,57 @@ func (tx *Tx) WithinTx(ctx context.Context, cb WithinTxCallback) error {
 		if err != nil {
 			_ = innerTx.Rollback(context.Background()) // Using context.Background() on purpose
 		}
+		hooks.finish(err == nil)
 	} else {
 		err = newError(err, "unable to start transaction")
 	}
-	return tx.db.handleError(err)
+	return tx.db.handleError(ctx, err)
+}
+
+// SetLocal changes a run-time parameter for the rest of the transaction by executing
+// `SET LOCAL <param> = <value>`. Both the parameter name and the value are safely quoted.
+func (tx *Tx) SetLocal(ctx context.Context, param string, value string) error {
+	_, err := tx.Exec(ctx, "SET LOCAL "+quoteParameterName(param)+" = "+QuoteLiteral(value))
+	return err
+}
+
+// Commit commits a transaction started with Database.Begin.
+func (tx *Tx) Commit(ctx context.Context) error {
+	if !tx.explicit {
+		return errors.New("transaction is managed by WithinTx")
+	}
+	ctx = tx.Context(ctx)
+
+	err := tx.hooks.runBeforeCommit(ctx)
+	if err == nil {
+		err = tx.tx.Commit(ctx)
+		if err != nil {
+			err = newError(err, "unable to commit db transaction")
+		}
+	} else {
+		err = newError(err, "before commit callback returned failure")
+		_ = tx.tx.Rollback(context.Background()) // Using context.Background() on purpose
+	}
+	tx.hooks.finish(err == nil)
+	return tx.db.handleError(ctx, err)
+}
+
+// Rollback rolls back a transaction started with Database.Begin.
+//
+// Calling Rollback after a successful Commit is safe and does nothing, so it can be deferred.
+func (tx *Tx) Rollback(ctx context.Context) error {
+	if !tx.explicit {
+		return errors.New("transaction is managed by WithinTx")
+	}
+	ctx = tx.stats.attach(ctx)
+
+	err := tx.tx.Rollback(ctx)
+	if err != nil {
+		if errors.Is(err, pgx.ErrTxClosed) {
+			return nil
+		}
+		err = newError(err, "unable to rollback db transaction")
+	}
+	tx.hooks.finish(false)
+	return tx.db.handleError(ctx, err)
 }
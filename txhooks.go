@@ -0,0 +1,156 @@
+// See the LICENSE file for license details.
+
+package postgres
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// -----------------------------------------------------------------------------
+
+// BeforeCommitCallback defines a callback called right before a transaction is committed.
+type BeforeCommitCallback = func(ctx context.Context) error
+
+type txHooksCtxKey struct{}
+
+type txHooks struct {
+	mutex        sync.Mutex
+	parent       *txHooks
+	beforeCommit []BeforeCommitCallback
+	onCommit     []func()
+	onRollback   []func()
+}
+
+// -----------------------------------------------------------------------------
+
+// BeforeCommit registers a callback that is executed right before the transaction bound to the
+// provided context is committed. If the callback returns an error, the transaction is rolled back.
+//
+// When called within a nested transaction, the callback is executed before the outermost
+// transaction commits.
+func BeforeCommit(ctx context.Context, cb BeforeCommitCallback) error {
+	h := getTxHooks(ctx)
+	if h == nil {
+		return errors.New("not within a transaction")
+	}
+
+	h.mutex.Lock()
+	h.beforeCommit = append(h.beforeCommit, cb)
+	h.mutex.Unlock()
+
+	// Done
+	return nil
+}
+
+// OnCommit registers a callback that is executed after the transaction bound to the provided
+// context is successfully committed. Callbacks are discarded if the transaction is rolled back.
+//
+// When called within a nested transaction, the callback is executed after the outermost
+// transaction commits.
+func OnCommit(ctx context.Context, cb func()) error {
+	h := getTxHooks(ctx)
+	if h == nil {
+		return errors.New("not within a transaction")
+	}
+
+	h.mutex.Lock()
+	h.onCommit = append(h.onCommit, cb)
+	h.mutex.Unlock()
+
+	// Done
+	return nil
+}
+
+// OnRollback registers a callback that is executed after the transaction bound to the provided
+// context is rolled back.
+func OnRollback(ctx context.Context, cb func()) error {
+	h := getTxHooks(ctx)
+	if h == nil {
+		return errors.New("not within a transaction")
+	}
+
+	h.mutex.Lock()
+	h.onRollback = append(h.onRollback, cb)
+	h.mutex.Unlock()
+
+	// Done
+	return nil
+}
+
+// -----------------------------------------------------------------------------
+
+func newTxHooks(parent *txHooks) *txHooks {
+	return &txHooks{
+		mutex:  sync.Mutex{},
+		parent: parent,
+	}
+}
+
+func getTxHooks(ctx context.Context) *txHooks {
+	h, _ := ctx.Value(txHooksCtxKey{}).(*txHooks)
+	return h
+}
+
+func (h *txHooks) attach(ctx context.Context) context.Context {
+	return context.WithValue(ctx, txHooksCtxKey{}, h)
+}
+
+func (h *txHooks) runBeforeCommit(ctx context.Context) error {
+	// Nested transactions defer the execution to the outermost one
+	if h.parent != nil {
+		return nil
+	}
+
+	// NOTE: A callback can register other callbacks so do not hold the lock while running them.
+	for idx := 0; ; idx++ {
+		h.mutex.Lock()
+		if idx >= len(h.beforeCommit) {
+			h.mutex.Unlock()
+			break
+		}
+		cb := h.beforeCommit[idx]
+		h.mutex.Unlock()
+
+		err := cb(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Done
+	return nil
+}
+
+func (h *txHooks) finish(committed bool) {
+	h.mutex.Lock()
+	beforeCommit := h.beforeCommit
+	onCommit := h.onCommit
+	onRollback := h.onRollback
+	h.beforeCommit = nil
+	h.onCommit = nil
+	h.onRollback = nil
+	h.mutex.Unlock()
+
+	if h.parent != nil {
+		if committed {
+			// Move callbacks to the outer transaction because it can still be rolled back
+			h.parent.mutex.Lock()
+			h.parent.beforeCommit = append(h.parent.beforeCommit, beforeCommit...)
+			h.parent.onCommit = append(h.parent.onCommit, onCommit...)
+			h.parent.onRollback = append(h.parent.onRollback, onRollback...)
+			h.parent.mutex.Unlock()
+			return
+		}
+	} else if committed {
+		for _, cb := range onCommit {
+			cb()
+		}
+		return
+	}
+
+	for _, cb := range onRollback {
+		cb()
+	}
+}
@@ -0,0 +1,68 @@
+// See the LICENSE file for license details.
+
+package postgres
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// -----------------------------------------------------------------------------
+
+// TxStats contains details about the transaction that was active when an error occurred.
+type TxStats struct {
+	// Statements is the number of statements executed within the transaction, including nested
+	// ones and the failing one.
+	Statements int
+	// Duration is the time elapsed since the transaction was started.
+	Duration time.Duration
+}
+
+type txStatsCtxKey struct{}
+
+type txStats struct {
+	startedAt  time.Time
+	statements atomic.Int32
+}
+
+// -----------------------------------------------------------------------------
+
+func newTxStats() *txStats {
+	return &txStats{
+		startedAt: time.Now(),
+	}
+}
+
+func getTxStats(ctx context.Context) *txStats {
+	s, _ := ctx.Value(txStatsCtxKey{}).(*txStats)
+	return s
+}
+
+func (s *txStats) attach(ctx context.Context) context.Context {
+	return context.WithValue(ctx, txStatsCtxKey{}, s)
+}
+
+// track increments the statement count and attaches the statistics to the context so errors
+// raised while executing the statement include them.
+func (s *txStats) track(ctx context.Context) context.Context {
+	s.statements.Add(1)
+	return s.attach(ctx)
+}
+
+// withTxStats adds the statistics of the transaction attached to the context, if any, to the error.
+func withTxStats(ctx context.Context, err error) {
+	var e *Error
+
+	if err == nil || !errors.As(err, &e) || e.TxStats != nil {
+		return
+	}
+	s := getTxStats(ctx)
+	if s != nil {
+		e.TxStats = &TxStats{
+			Statements: int(s.statements.Load()),
+			Duration:   time.Since(s.startedAt),
+		}
+	}
+}
@@ -0,0 +1,404 @@
+// See the LICENSE file for license details.
+
+package postgres
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// -----------------------------------------------------------------------------
+
+// BigInt is an arbitrary precision integer that can be read from and written to NUMERIC columns
+// without loss. Use *BigInt destinations to read nullable columns.
+type BigInt struct {
+	big.Int
+}
+
+// BigRat is an arbitrary precision rational number that can be read from and written to NUMERIC
+// columns without loss. Only values with a finite decimal representation can be written.
+// Use *BigRat destinations to read nullable columns.
+type BigRat struct {
+	big.Rat
+}
+
+// TrimmedString is a string that has its trailing spaces removed when read from the database.
+// Useful to read fixed-length CHAR(n) columns which are returned space-padded. Use *TrimmedString
+// destinations to read nullable columns.
+type TrimmedString string
+
+// Interval represents a PostgreSQL interval with its raw components. Months and days are kept
+// separated from the time part because they do not have a fixed length. Use *Interval destinations
+// to read nullable columns.
+//
+// Interval columns can also be read into time.Duration variables if the months and days
+// approximation described in Interval.Duration is acceptable.
+type Interval struct {
+	Months       int32
+	Days         int32
+	Microseconds int64
+}
+
+// Money represents a value of the PostgreSQL money type as an amount of cents (hundredths of the
+// currency unit). Use *Money destinations to read nullable columns.
+//
+// The server formats money values using the lc_monetary setting, so the parser accepts currency
+// symbols, group separators, both decimal separators and negative amounts expressed with a minus
+// sign or parentheses. Values are written as plain decimal numbers which require lc_monetary to
+// use a dot as the decimal separator. A last separator followed by three digits is considered a
+// group separator. New schemas should use NUMERIC instead.
+type Money int64
+
+// BitString represents a value of the PostgreSQL bit(n) and varbit types. Bits are numbered from
+// left to right starting at zero, like the server's get_bit and set_bit functions do. Use
+// *BitString destinations to read nullable columns.
+type BitString struct {
+	bytes  []byte
+	length int
+}
+
+// -----------------------------------------------------------------------------
+
+const (
+	microsecondsPerDay   = 24 * 60 * 60 * 1000000
+	microsecondsPerMonth = 30 * microsecondsPerDay
+)
+
+var big10 = big.NewInt(10)
+
+// -----------------------------------------------------------------------------
+
+// ScanNumeric implements the pgtype.NumericScanner interface.
+func (b *BigInt) ScanNumeric(v pgtype.Numeric) error {
+	if !v.Valid {
+		return errors.New("cannot scan NULL into BigInt")
+	}
+	if v.NaN || v.InfinityModifier != pgtype.Finite {
+		return errors.New("cannot scan NaN or infinity into BigInt")
+	}
+
+	b.Int.Set(v.Int)
+	if v.Exp > 0 {
+		b.Int.Mul(&b.Int, new(big.Int).Exp(big10, big.NewInt(int64(v.Exp)), nil))
+	} else if v.Exp < 0 {
+		remainder := new(big.Int)
+		b.Int.QuoRem(&b.Int, new(big.Int).Exp(big10, big.NewInt(int64(-v.Exp)), nil), remainder)
+		if remainder.Sign() != 0 {
+			return errors.New("cannot scan a non-integer value into BigInt")
+		}
+	}
+
+	// Done
+	return nil
+}
+
+// NumericValue implements the pgtype.NumericValuer interface.
+func (b BigInt) NumericValue() (pgtype.Numeric, error) {
+	return pgtype.Numeric{
+		Int:   new(big.Int).Set(&b.Int),
+		Valid: true,
+	}, nil
+}
+
+// ScanNumeric implements the pgtype.NumericScanner interface.
+func (r *BigRat) ScanNumeric(v pgtype.Numeric) error {
+	if !v.Valid {
+		return errors.New("cannot scan NULL into BigRat")
+	}
+	if v.NaN || v.InfinityModifier != pgtype.Finite {
+		return errors.New("cannot scan NaN or infinity into BigRat")
+	}
+
+	r.Rat.SetInt(v.Int)
+	if v.Exp > 0 {
+		r.Rat.Mul(&r.Rat, new(big.Rat).SetInt(new(big.Int).Exp(big10, big.NewInt(int64(v.Exp)), nil)))
+	} else if v.Exp < 0 {
+		r.Rat.Quo(&r.Rat, new(big.Rat).SetInt(new(big.Int).Exp(big10, big.NewInt(int64(-v.Exp)), nil)))
+	}
+
+	// Done
+	return nil
+}
+
+// NumericValue implements the pgtype.NumericValuer interface.
+func (r BigRat) NumericValue() (pgtype.Numeric, error) {
+	// A rational number has a finite decimal representation only if the denominator is of the
+	// form 2^a * 5^b. In that case, multiplying by 10^max(a,b) gives an integer.
+	den := new(big.Int).Set(r.Rat.Denom())
+	twos := 0
+	fives := 0
+	two := big.NewInt(2)
+	five := big.NewInt(5)
+	remainder := new(big.Int)
+	for {
+		q, m := new(big.Int).QuoRem(den, two, remainder)
+		if m.Sign() != 0 {
+			break
+		}
+		den = q
+		twos += 1
+	}
+	for {
+		q, m := new(big.Int).QuoRem(den, five, remainder)
+		if m.Sign() != 0 {
+			break
+		}
+		den = q
+		fives += 1
+	}
+	if den.Cmp(big.NewInt(1)) != 0 {
+		return pgtype.Numeric{}, errors.New("value cannot be represented as a decimal number")
+	}
+
+	exp := twos
+	if fives > exp {
+		exp = fives
+	}
+	num := new(big.Int).Mul(r.Rat.Num(), new(big.Int).Exp(big10, big.NewInt(int64(exp)), nil))
+	num.Quo(num, r.Rat.Denom())
+
+	// Done
+	return pgtype.Numeric{
+		Int:   num,
+		Exp:   int32(-exp),
+		Valid: true,
+	}, nil
+}
+
+// ScanText implements the pgtype.TextScanner interface.
+func (s *TrimmedString) ScanText(v pgtype.Text) error {
+	if !v.Valid {
+		return errors.New("cannot scan NULL into TrimmedString")
+	}
+	*s = TrimmedString(strings.TrimRight(v.String, " "))
+	return nil
+}
+
+// TextValue implements the pgtype.TextValuer interface.
+func (s TrimmedString) TextValue() (pgtype.Text, error) {
+	return pgtype.Text{
+		String: string(s),
+		Valid:  true,
+	}, nil
+}
+
+// Duration converts the interval to a time.Duration assuming months have 30 days and days have
+// 24 hours. This is the same conversion used when an interval column is read into a time.Duration.
+func (i Interval) Duration() time.Duration {
+	us := int64(i.Months)*microsecondsPerMonth + int64(i.Days)*microsecondsPerDay + i.Microseconds
+	return time.Duration(us) * time.Microsecond
+}
+
+// ScanInterval implements the pgtype.IntervalScanner interface.
+func (i *Interval) ScanInterval(v pgtype.Interval) error {
+	if !v.Valid {
+		return errors.New("cannot scan NULL into Interval")
+	}
+	i.Months = v.Months
+	i.Days = v.Days
+	i.Microseconds = v.Microseconds
+	return nil
+}
+
+// IntervalValue implements the pgtype.IntervalValuer interface.
+func (i Interval) IntervalValue() (pgtype.Interval, error) {
+	return pgtype.Interval{
+		Months:       i.Months,
+		Days:         i.Days,
+		Microseconds: i.Microseconds,
+		Valid:        true,
+	}, nil
+}
+
+// ScanText implements the pgtype.TextScanner interface.
+func (m *Money) ScanText(v pgtype.Text) error {
+	if !v.Valid {
+		return errors.New("cannot scan NULL into Money")
+	}
+
+	// Locate the decimal separator, if any
+	fracDigits := 0
+	sepIdx := strings.LastIndexAny(v.String, ".,")
+	if sepIdx >= 0 {
+		for idx := sepIdx + 1; idx < len(v.String) && v.String[idx] >= '0' && v.String[idx] <= '9'; idx++ {
+			fracDigits += 1
+		}
+		if fracDigits == 3 {
+			fracDigits = 0 // Group separator
+		} else if fracDigits > 2 {
+			return errors.New("invalid money value")
+		}
+	}
+
+	// Parse the digits
+	var value uint64
+
+	negative := false
+	hasDigits := false
+	for _, ch := range v.String {
+		switch {
+		case ch >= '0' && ch <= '9':
+			if value > (math.MaxInt64+1)/10 {
+				return errors.New("money value out of range")
+			}
+			value = value*10 + uint64(ch-'0')
+			hasDigits = true
+		case ch == '-' || ch == '(':
+			negative = true
+		}
+	}
+	if !hasDigits {
+		return errors.New("invalid money value")
+	}
+	for ; fracDigits < 2; fracDigits++ {
+		if value > (math.MaxInt64+1)/10 {
+			return errors.New("money value out of range")
+		}
+		value *= 10
+	}
+	if value > math.MaxInt64+1 || (value == math.MaxInt64+1 && !negative) {
+		return errors.New("money value out of range")
+	}
+
+	if negative {
+		*m = Money(-int64(value-1) - 1)
+	} else {
+		*m = Money(value)
+	}
+	return nil
+}
+
+// TextValue implements the pgtype.TextValuer interface.
+func (m Money) TextValue() (pgtype.Text, error) {
+	return pgtype.Text{
+		String: m.String(),
+		Valid:  true,
+	}, nil
+}
+
+// String returns the value as a decimal number with two fractional digits.
+func (m Money) String() string {
+	value := uint64(m)
+	sign := ""
+	if m < 0 {
+		value = uint64(-(m + 1)) + 1
+		sign = "-"
+	}
+	cents := strconv.FormatUint(value%100, 10)
+	if len(cents) < 2 {
+		cents = "0" + cents
+	}
+	return sign + strconv.FormatUint(value/100, 10) + "." + cents
+}
+
+// NewBitString creates a bit string of the given length with all the bits cleared.
+func NewBitString(length int) BitString {
+	if length < 0 {
+		length = 0
+	}
+	return BitString{
+		bytes:  make([]byte, (length+7)/8),
+		length: length,
+	}
+}
+
+// ParseBitString creates a bit string from its textual representation, for e.g. "10110".
+func ParseBitString(s string) (BitString, error) {
+	b := NewBitString(len(s))
+	for idx := 0; idx < len(s); idx++ {
+		switch s[idx] {
+		case '0':
+		case '1':
+			b.SetBit(idx, true)
+		default:
+			return BitString{}, errors.New("invalid bit string")
+		}
+	}
+	return b, nil
+}
+
+// Len returns the number of bits.
+func (b BitString) Len() int {
+	return b.length
+}
+
+// Bit returns the value of the bit at the given position. Out of range positions return false.
+func (b BitString) Bit(idx int) bool {
+	if idx < 0 || idx >= b.length {
+		return false
+	}
+	return b.bytes[idx/8]&(0x80>>(idx%8)) != 0
+}
+
+// SetBit changes the value of the bit at the given position. Out of range positions are ignored.
+func (b *BitString) SetBit(idx int, value bool) {
+	if idx < 0 || idx >= b.length {
+		return
+	}
+	if value {
+		b.bytes[idx/8] |= 0x80 >> (idx % 8)
+	} else {
+		b.bytes[idx/8] &^= 0x80 >> (idx % 8)
+	}
+}
+
+// String returns the textual representation of the bit string, for e.g. "10110".
+func (b BitString) String() string {
+	sb := strings.Builder{}
+	sb.Grow(b.length)
+	for idx := 0; idx < b.length; idx++ {
+		if b.Bit(idx) {
+			_ = sb.WriteByte('1')
+		} else {
+			_ = sb.WriteByte('0')
+		}
+	}
+	return sb.String()
+}
+
+// ScanBits implements the pgtype.BitsScanner interface.
+func (b *BitString) ScanBits(v pgtype.Bits) error {
+	if !v.Valid {
+		return errors.New("cannot scan NULL into BitString")
+	}
+	if v.Len < 0 || int(v.Len) > len(v.Bytes)*8 {
+		return errors.New("invalid bit string length")
+	}
+	b.bytes = append(make([]byte, 0, len(v.Bytes)), v.Bytes...)
+	b.length = int(v.Len)
+	return nil
+}
+
+// BitsValue implements the pgtype.BitsValuer interface.
+func (b BitString) BitsValue() (pgtype.Bits, error) {
+	return pgtype.Bits{
+		Bytes: b.bytes,
+		Len:   int32(b.length),
+		Valid: true,
+	}, nil
+}
+
+// ResolveType returns the OID of the given data type name, for e.g. "int4" or "myschema.mytype".
+// The name is resolved using the current search path. If the type does not exist, a NoRowsError
+// is returned.
+func (db *Database) ResolveType(ctx context.Context, typeName string) (uint32, error) {
+	var oid *uint32
+
+	err := db.QueryRow(ctx, `SELECT to_regtype($1)::oid`, typeName).Scan(&oid)
+	if err != nil {
+		return 0, err
+	}
+	if oid == nil {
+		return 0, errNoRows
+	}
+
+	// Done
+	return *oid, nil
+}
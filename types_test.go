@@ -0,0 +1,439 @@
+// See the LICENSE file for license details.
+
+package postgres_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/mxmauro/go-postgres/v2"
+)
+
+// -----------------------------------------------------------------------------
+
+func TestBigNumericConversion(t *testing.T) {
+	var r postgres.BigRat
+	var r2 postgres.BigRat
+
+	r.SetString("-12345678901234567890.123456789")
+	n, err := r.NumericValue()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	err = r2.ScanNumeric(n)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if r.Cmp(&r2.Rat) != 0 {
+		t.Fatalf("value mismatch [got=%v/expected=%v]", r2.RatString(), r.RatString())
+	}
+
+	r.SetFrac64(1, 3)
+	_, err = r.NumericValue()
+	if err == nil {
+		t.Fatal("non-decimal value was accepted")
+	}
+}
+
+func TestBigNumeric(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDatabase(ctx, t)
+	defer db.Close()
+
+	// Integers at the extremes of NUMERIC(38,0)
+	for _, s := range []string{"99999999999999999999999999999999999999", "-99999999999999999999999999999999999999", "0"} {
+		var v postgres.BigInt
+		var readV postgres.BigInt
+
+		v.SetString(s, 10)
+		err := db.QueryRow(ctx, `SELECT $1::NUMERIC(38,0)`, v).Scan(&readV)
+		if err != nil {
+			t.Fatalf("unable to round-trip big integer [value=%v/err=%v]", s, err.Error())
+		}
+		if v.Cmp(&readV.Int) != 0 {
+			t.Fatalf("big integer mismatch [got=%v/expected=%v]", readV.String(), s)
+		}
+	}
+
+	// Rationals
+	for _, s := range []string{"12345678901234567890.123456789012345678", "-0.000000000000000000000001", "42"} {
+		var v postgres.BigRat
+		var readV postgres.BigRat
+
+		v.SetString(s)
+		err := db.QueryRow(ctx, `SELECT $1::NUMERIC`, v).Scan(&readV)
+		if err != nil {
+			t.Fatalf("unable to round-trip big rational [value=%v/err=%v]", s, err.Error())
+		}
+		if v.Cmp(&readV.Rat) != 0 {
+			t.Fatalf("big rational mismatch [got=%v/expected=%v]", readV.RatString(), s)
+		}
+	}
+
+	// NULL handling
+	var nv *postgres.BigInt
+	err := db.QueryRow(ctx, `SELECT NULL::NUMERIC`).Scan(&nv)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if nv != nil {
+		t.Fatalf("expected nil value [got=%v]", nv.String())
+	}
+}
+
+func TestTrimmedString(t *testing.T) {
+	var s postgres.TrimmedString
+	var ns *postgres.TrimmedString
+
+	ctx := context.Background()
+	db := openTestDatabase(ctx, t)
+	defer db.Close()
+
+	err := db.QueryRow(ctx, `SELECT 'abc'::CHAR(10), NULL::CHAR(10)`).Scan(&s, &ns)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if s != "abc" {
+		t.Fatalf("value mismatch [got='%v'/expected='abc']", s)
+	}
+	if ns != nil {
+		t.Fatalf("expected nil value [got='%v']", *ns)
+	}
+}
+
+func TestInterval(t *testing.T) {
+	var d time.Duration
+	var d2 time.Duration
+	var i postgres.Interval
+
+	ctx := context.Background()
+	db := openTestDatabase(ctx, t)
+	defer db.Close()
+
+	err := db.QueryRow(ctx, `SELECT '1 hour 30 minutes'::INTERVAL, '1.5 seconds'::INTERVAL, '1 month 2 days 3.25 seconds'::INTERVAL`).Scan(&d, &d2, &i)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if d != 90*time.Minute {
+		t.Fatalf("duration mismatch [got=%v/expected=%v]", d, 90*time.Minute)
+	}
+	if d2 != 1500*time.Millisecond {
+		t.Fatalf("duration mismatch [got=%v/expected=%v]", d2, 1500*time.Millisecond)
+	}
+	if i.Months != 1 || i.Days != 2 || i.Microseconds != 3250000 {
+		t.Fatalf("interval mismatch [got=%+v]", i)
+	}
+
+	// Round-trip
+	err = db.QueryRow(ctx, `SELECT $1::INTERVAL, $2::INTERVAL`, d2, i).Scan(&d, &i)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if d != 1500*time.Millisecond {
+		t.Fatalf("duration mismatch [got=%v/expected=%v]", d, 1500*time.Millisecond)
+	}
+	if i.Months != 1 || i.Days != 2 || i.Microseconds != 3250000 {
+		t.Fatalf("interval mismatch [got=%+v]", i)
+	}
+}
+
+func TestMoneyConversion(t *testing.T) {
+	for _, tc := range []struct {
+		value    string
+		expected postgres.Money
+	}{
+		{`$1,234.56`, 123456},
+		{`-$1,234.56`, -123456},
+		{`($0.05)`, -5},
+		{`1.234,5 €`, 123450},
+		{`¥1,234`, 123400},
+		{`$92,233,720,368,547,758.07`, math.MaxInt64},
+		{`-$92,233,720,368,547,758.08`, math.MinInt64},
+	} {
+		var m postgres.Money
+
+		err := m.ScanText(pgtype.Text{String: tc.value, Valid: true})
+		if err != nil {
+			t.Fatalf("unable to parse money value [value=%v] [err=%v]", tc.value, err.Error())
+		}
+		if m != tc.expected {
+			t.Fatalf("money mismatch [got=%v/expected=%v]", m, tc.expected)
+		}
+	}
+
+	var m postgres.Money
+	err := m.ScanText(pgtype.Text{String: `$92,233,720,368,547,758.08`, Valid: true})
+	if err == nil {
+		t.Fatal("out of range money value was accepted")
+	}
+
+	if s := postgres.Money(math.MinInt64).String(); s != "-92233720368547758.08" {
+		t.Fatalf("money string mismatch [got=%v]", s)
+	}
+	if s := postgres.Money(-5).String(); s != "-0.05" {
+		t.Fatalf("money string mismatch [got=%v]", s)
+	}
+}
+
+func TestMoney(t *testing.T) {
+	var m postgres.Money
+	var m2 postgres.Money
+
+	ctx := context.Background()
+	db := openTestDatabase(ctx, t)
+	defer db.Close()
+
+	err := db.QueryRow(ctx, `SELECT '-1234.56'::NUMERIC::MONEY, $1::MONEY`, postgres.Money(9876543210)).Scan(&m, &m2)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if m != -123456 {
+		t.Fatalf("money mismatch [got=%v/expected=-1234.56]", m)
+	}
+	if m2 != 9876543210 {
+		t.Fatalf("money mismatch [got=%v/expected=98765432.10]", m2)
+	}
+}
+
+func TestSqlScanner(t *testing.T) {
+	var s upperCaseString
+	var n evenNumber
+
+	ctx := context.Background()
+	db := openTestDatabase(ctx, t)
+	defer db.Close()
+
+	err := db.QueryRow(ctx, `SELECT 'abc'::TEXT, 42::BIGINT`).Scan(&s, &n)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if s != "ABC" {
+		t.Fatalf("value mismatch [got='%v'/expected='ABC']", s)
+	}
+	if n != 42 {
+		t.Fatalf("value mismatch [got=%v/expected=42]", n)
+	}
+
+	err = db.QueryRow(ctx, `SELECT 41::BIGINT`).Scan(&n)
+	if err == nil {
+		t.Fatal("scanner error was not returned")
+	}
+}
+
+func TestSqlValuer(t *testing.T) {
+	var s string
+	var s2 string
+
+	ctx := context.Background()
+	db := openTestDatabase(ctx, t)
+	defer db.Close()
+
+	err := db.QueryRow(ctx, `SELECT $1::TEXT, $2::TEXT`, yesNo(true), point2D{X: 1, Y: 2}).Scan(&s, &s2)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if s != "Y" {
+		t.Fatalf("value mismatch [got='%v'/expected='Y']", s)
+	}
+	if s2 != "(1,2)" {
+		t.Fatalf("value mismatch [got='%v'/expected='(1,2)']", s2)
+	}
+}
+
+func TestBitStringConversion(t *testing.T) {
+	b, err := postgres.ParseBitString("1011000001")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if b.Len() != 10 || !b.Bit(0) || b.Bit(1) || !b.Bit(9) || b.Bit(10) {
+		t.Fatalf("bit string mismatch [got=%v]", b.String())
+	}
+	b.SetBit(1, true)
+	b.SetBit(9, false)
+	if s := b.String(); s != "1111000000" {
+		t.Fatalf("bit string mismatch [got=%v/expected=1111000000]", s)
+	}
+
+	_, err = postgres.ParseBitString("10a")
+	if err == nil {
+		t.Fatal("invalid bit string did not fail")
+	}
+}
+
+func TestBitString(t *testing.T) {
+	var fixed postgres.BitString
+	var variable postgres.BitString
+	var null *postgres.BitString
+
+	ctx := context.Background()
+	db := openTestDatabase(ctx, t)
+	defer db.Close()
+
+	value := postgres.NewBitString(12)
+	value.SetBit(0, true)
+	value.SetBit(11, true)
+
+	err := db.QueryRow(ctx, `SELECT B'10100101'::BIT(8), $1::VARBIT, NULL::VARBIT`, value).Scan(&fixed, &variable, &null)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if fixed.String() != "10100101" {
+		t.Fatalf("bit value mismatch [got=%v/expected=10100101]", fixed.String())
+	}
+	if variable.String() != "100000000001" {
+		t.Fatalf("varbit value mismatch [got=%v/expected=100000000001]", variable.String())
+	}
+	if null != nil {
+		t.Fatalf("null value mismatch [got=%v]", null)
+	}
+}
+
+func TestTextSearch(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDatabase(ctx, t)
+	defer db.Close()
+
+	err := db.WithinTx(ctx, func(ctx context.Context, tx *postgres.Tx) error {
+		var vector string
+		var query string
+
+		_, err := tx.Exec(ctx, `CREATE TEMP TABLE go_postgres_tsv_test (id INT, body TSVECTOR) ON COMMIT DROP`)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(
+			ctx,
+			`INSERT INTO go_postgres_tsv_test VALUES (1, to_tsvector('english', $1)), (2, to_tsvector('english', $2))`,
+			"The quick brown fox", "A lazy dog",
+		)
+		if err != nil {
+			return err
+		}
+
+		ids := make([]int, 0)
+		err = tx.QueryRows(
+			ctx,
+			`SELECT id FROM go_postgres_tsv_test WHERE body @@ `+postgres.PlainTSQuery(1, "english"),
+			"foxes' & (quick",
+		).Do(func(ctx context.Context, row postgres.Row) (bool, error) {
+			var id int
+
+			err := row.Scan(&id)
+			ids = append(ids, id)
+			return true, err
+		})
+		if err != nil {
+			return err
+		}
+		if len(ids) != 1 || ids[0] != 1 {
+			return fmt.Errorf("search results mismatch [got=%v]", ids)
+		}
+
+		err = tx.QueryRow(
+			ctx, `SELECT body, `+postgres.PlainTSQuery(1, "english")+` FROM go_postgres_tsv_test WHERE id = 2`, "lazy dogs",
+		).Scan(&vector, &query)
+		if err != nil {
+			return err
+		}
+		if vector != "'dog':3 'lazi':2" || query != "'lazi' & 'dog'" {
+			return fmt.Errorf("text search values mismatch [vector=%v/query=%v]", vector, query)
+		}
+
+		// Done
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+}
+
+func TestOIDTypes(t *testing.T) {
+	var oid uint32
+	var typeName string
+	var className string
+
+	ctx := context.Background()
+	db := openTestDatabase(ctx, t)
+	defer db.Close()
+
+	err := db.QueryRow(ctx, `SELECT 'int4'::REGTYPE::OID, 'int4'::REGTYPE, 'pg_class'::REGCLASS`).Scan(
+		&oid, &typeName, &className,
+	)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if oid != 23 {
+		t.Fatalf("oid mismatch [got=%v/expected=23]", oid)
+	}
+	if typeName != "integer" {
+		t.Fatalf("regtype mismatch [got='%v'/expected='integer']", typeName)
+	}
+	if className != "pg_class" {
+		t.Fatalf("regclass mismatch [got='%v'/expected='pg_class']", className)
+	}
+
+	oid, err = db.ResolveType(ctx, "int4")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if oid != 23 {
+		t.Fatalf("resolved oid mismatch [got=%v/expected=23]", oid)
+	}
+
+	_, err = db.ResolveType(ctx, "go_postgres_non_existent_type")
+	if !postgres.IsNoRowsError(err) {
+		t.Fatalf("unexpected error resolving a non-existent type [err=%v]", err)
+	}
+}
+
+// -----------------------------------------------------------------------------
+
+type upperCaseString string
+
+func (s *upperCaseString) Scan(src interface{}) error {
+	v, ok := src.(string)
+	if !ok {
+		return fmt.Errorf("unexpected type %T", src)
+	}
+	*s = upperCaseString(strings.ToUpper(v))
+	return nil
+}
+
+type evenNumber int
+
+func (n *evenNumber) Scan(src interface{}) error {
+	v, ok := src.(int64)
+	if !ok {
+		return fmt.Errorf("unexpected type %T", src)
+	}
+	if v%2 != 0 {
+		return errors.New("odd number")
+	}
+	*n = evenNumber(v)
+	return nil
+}
+
+type yesNo bool
+
+func (b yesNo) Value() (driver.Value, error) {
+	if b {
+		return "Y", nil
+	}
+	return "N", nil
+}
+
+type point2D struct {
+	X int
+	Y int
+}
+
+func (p point2D) Value() (driver.Value, error) {
+	return fmt.Sprintf("(%d,%d)", p.X, p.Y), nil
+}
@@ -0,0 +1,62 @@
+// See the LICENSE file for license details.
+
+package postgres
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// -----------------------------------------------------------------------------
+
+// UpdateMap builds and executes an `UPDATE ... SET ... WHERE ...` sentence using the keys of the set
+// and where maps as column names and their values as parameters. Conditions are joined with AND.
+// It returns the number of affected rows.
+//
+// Keys are sorted so the same set of columns always produces the same sentence. The where map cannot
+// be empty in order to avoid updating the whole table by mistake.
+func (db *Database) UpdateMap(
+	ctx context.Context, tableName string, set map[string]interface{}, where map[string]interface{},
+) (int64, error) {
+	if len(set) == 0 {
+		return 0, errors.New("no columns to update specified")
+	}
+	if len(where) == 0 {
+		return 0, errors.New("no conditions specified")
+	}
+
+	args := make([]interface{}, 0, len(set)+len(where))
+
+	sb := strings.Builder{}
+	_, _ = sb.WriteString("UPDATE " + db.quoteTableName(tableName) + " SET ")
+	for idx, col := range getSortedMapKeys(set) {
+		if idx > 0 {
+			_, _ = sb.WriteString(", ")
+		}
+		args = append(args, set[col])
+		_, _ = sb.WriteString(QuoteIdentifier(col) + " = $" + strconv.Itoa(len(args)))
+	}
+	_, _ = sb.WriteString(" WHERE ")
+	for idx, col := range getSortedMapKeys(where) {
+		if idx > 0 {
+			_, _ = sb.WriteString(" AND ")
+		}
+		args = append(args, where[col])
+		_, _ = sb.WriteString(QuoteIdentifier(col) + " = $" + strconv.Itoa(len(args)))
+	}
+
+	// Execute the sentence
+	return db.Exec(ctx, sb.String(), args...)
+}
+
+func getSortedMapKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,75 @@
+// See the LICENSE file for license details.
+
+package postgres
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// -----------------------------------------------------------------------------
+
+// validateSql runs the development checks enabled in the options. If DebugValidate is set, the
+// server is asked to parse and analyze the statement without executing it. If conn is nil, a
+// connection is acquired from the pool.
+func (db *Database) validateSql(ctx context.Context, conn *pgconn.PgConn, sql string, args []interface{}) error {
+	err := db.checkPlaceholders(sql, args)
+	if err != nil {
+		return err
+	}
+
+	if !db.debugValidate {
+		return nil
+	}
+	return db.prepareSql(ctx, conn, sql)
+}
+
+// validateExecSql works like validateSql but does not send statements without arguments to the
+// server because they are executed using the simple protocol and may contain multiple commands
+// that cannot be prepared.
+func (db *Database) validateExecSql(ctx context.Context, conn *pgconn.PgConn, sql string, args []interface{}) error {
+	if len(args) == 0 {
+		return db.checkPlaceholders(sql, args)
+	}
+	return db.validateSql(ctx, conn, sql, args)
+}
+
+func (db *Database) checkPlaceholders(sql string, args []interface{}) error {
+	if !db.debugPlaceholders {
+		return nil
+	}
+	err := checkSqlPlaceholders(sql, args)
+	if err != nil {
+		// NOTE: Build the error here because newError returns non-postgres errors as is.
+		return &Error{
+			message: "statement validation failed [sql=" + db.formatErrorSql(sql) + "]",
+			err:     err,
+		}
+	}
+
+	// Done
+	return nil
+}
+
+func (db *Database) prepareSql(ctx context.Context, conn *pgconn.PgConn, sql string) error {
+	if conn == nil {
+		poolConn, err := db.pool.Load().Acquire(ctx)
+		if err != nil {
+			// Let the real execution report the failure
+			return nil
+		}
+		defer poolConn.Release()
+
+		conn = poolConn.Conn().PgConn()
+	}
+
+	// NOTE: Use the unnamed statement so nothing is kept in the server.
+	_, err := conn.Prepare(ctx, "", sql, nil)
+	if err != nil {
+		return newError(err, "statement validation failed [sql="+db.formatErrorSql(sql)+"]")
+	}
+
+	// Done
+	return nil
+}
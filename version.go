@@ -0,0 +1,56 @@
+// See the LICENSE file for license details.
+
+package postgres
+
+import (
+	"context"
+)
+
+// -----------------------------------------------------------------------------
+
+// ServerVersion contains the version of the database server.
+type ServerVersion struct {
+	Major int
+	Minor int
+	// Full contains the complete version string as returned by `SELECT version()`.
+	Full string
+}
+
+// -----------------------------------------------------------------------------
+
+// AtLeast returns true if the server version is equal or greater than the specified one.
+func (v ServerVersion) AtLeast(major int, minor int) bool {
+	return v.Major > major || (v.Major == major && v.Minor >= minor)
+}
+
+// ServerVersion returns the version of the database server. The value is retrieved on the first
+// call and cached for subsequent ones.
+func (db *Database) ServerVersion(ctx context.Context) (ServerVersion, error) {
+	var versionNum int
+
+	db.serverVersion.mutex.Lock()
+	defer db.serverVersion.mutex.Unlock()
+
+	if db.serverVersion.value != nil {
+		return *db.serverVersion.value, nil
+	}
+
+	v := ServerVersion{}
+	err := db.QueryRow(ctx, `SELECT current_setting('server_version_num')::int, version()`).Scan(&versionNum, &v.Full)
+	if err != nil {
+		return ServerVersion{}, err
+	}
+
+	// Starting with v10, the number is composed by major*10000+minor. Before, the major version
+	// had two components, for e.g.: 9.6.24 is 90624.
+	v.Major = versionNum / 10000
+	if v.Major >= 10 {
+		v.Minor = versionNum % 10000
+	} else {
+		v.Minor = (versionNum / 100) % 100
+	}
+	db.serverVersion.value = &v
+
+	// Done
+	return v, nil
+}